@@ -0,0 +1,88 @@
+package imgur
+
+import (
+	"fmt"
+)
+
+// AlbumService handles communication with the album related
+// methods of the Imgur API.
+//
+// API docs: https://api.imgur.com/endpoints/album
+type AlbumService struct {
+	client *Client
+}
+
+type Album struct {
+	ID          string  `json:"id"`           // The ID for the album
+	Title       string  `json:"title"`        // The title of the album in the gallery
+	Description string  `json:"description"`  // The description of the album in the gallery
+	DateTime    int     `json:"datetime"`     // Time inserted into the gallery, epoch time
+	Cover       string  `json:"cover"`        // The ID of the album cover image
+	CoverWidth  int     `json:"cover_width"`  // The width, in pixels, of the album cover image
+	CoverHeight int     `json:"cover_height"` // The height, in pixels, of the album cover image
+	AccountUrl  string  `json:"account_url"`  // The account username or null if it's anonymous.
+	Privacy     string  `json:"privacy"`      // The privacy level of the album, you can only view public if not logged in as album owner
+	Layout      string  `json:"layout"`       // The view layout of the album.
+	Views       int     `json:"views"`        // The number of album views
+	Link        string  `json:"link"`         // The URL link to the album
+	Nsfw        bool    `json:"nsfw"`         // Indicates if the album has been marked as nsfw or not.
+	Section     string  `json:"section"`      // If the album has been categorized by our backend then this will contain the section the album belongs in.
+	DeleteHash  string  `json:"deletehash"`   // OPTIONAL, the deletehash, if you're logged in as the album owner
+	Favorite    bool    `json:"favorite"`     // Indicates if the current user favorited the album
+	InGallery   bool    `json:"in_gallery"`   // True if the album has been submitted to the gallery
+	AccountID   int     `json:"account_id"`   // The account ID of the album owner, zero if anonymous
+	ImagesCount int     `json:"images_count"` // The total number of images in the album
+	Images      []Image `json:"images"`       // An array of all the images in the album (only available when requesting the direct album)
+}
+
+// Info retrieves information about an album, including its images.
+func (s *AlbumService) Info(id string) (*Album, error) {
+	if id == "" {
+		return nil, fmt.Errorf("album id must be provided")
+	}
+
+	url := fmt.Sprintf("album/%s", id)
+	req, err := s.client.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type albumResponse struct {
+		Data *Album
+		Result
+	}
+	response := &albumResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
+
+// Images retrieves information about the images in an album.
+func (s *AlbumService) Images(id string) ([]Image, error) {
+	if id == "" {
+		return nil, fmt.Errorf("album id must be provided")
+	}
+
+	url := fmt.Sprintf("album/%s/images", id)
+	req, err := s.client.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type imagesResponse struct {
+		Data []Image
+		Result
+	}
+	response := &imagesResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
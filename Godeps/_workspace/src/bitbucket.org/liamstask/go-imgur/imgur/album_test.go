@@ -0,0 +1,67 @@
+package imgur
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+const (
+	albumInfoResponse = `{"data":{"id":"Wu0zw","title":"Sad Keanu is Now an Action Figure!","description":null,"datetime":1390524925,"cover":"LAobnLK","cover_width":625,"cover_height":944,"account_url":"literallyannperkins","privacy":"public","layout":"blog","views":770,"link":"http:\/\/imgur.com\/a\/Wu0zw","nsfw":false,"images_count":2,"images":[{"id":"LAobnLK","title":null,"description":null,"datetime":1390524873,"type":"image\/jpeg","animated":false,"width":625,"height":944,"size":78522,"views":1541,"bandwidth":121002402,"link":"http:\/\/i.imgur.com\/LAobnLK.jpg"},{"id":"qO2Oq5l","title":null,"description":null,"datetime":1390524874,"type":"image\/jpeg","animated":false,"width":625,"height":469,"size":46329,"views":1378,"bandwidth":63841362,"link":"http:\/\/i.imgur.com\/qO2Oq5l.jpg"}]},"success":true,"status":200}`
+
+	albumImagesResponse = `{"data":[{"id":"LAobnLK","title":null,"description":null,"datetime":1390524873,"type":"image\/jpeg","animated":false,"width":625,"height":944,"size":78522,"views":1541,"bandwidth":121002402,"link":"http:\/\/i.imgur.com\/LAobnLK.jpg"}],"success":true,"status":200}`
+)
+
+func TestAlbumInfo(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/album/Wu0zw", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, albumInfoResponse)
+	})
+
+	album, err := client.Album.Info("Wu0zw")
+	if err != nil {
+		t.Errorf("Album.Info returned error: %v", err)
+	}
+
+	want := "LAobnLK"
+	if album.Cover != want {
+		t.Errorf("Album.Info returned cover %+v, want %+v", album.Cover, want)
+	}
+
+	if len(album.Images) != 2 {
+		t.Errorf("Album.Info returned %+v images, want %+v", len(album.Images), 2)
+	}
+}
+
+func TestAlbumImages(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/album/Wu0zw/images", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, albumImagesResponse)
+	})
+
+	images, err := client.Album.Images("Wu0zw")
+	if err != nil {
+		t.Errorf("Album.Images returned error: %v", err)
+	}
+
+	if len(images) == 0 {
+		t.Fatalf("Album.Images returned %+v, want %+v", len(images), 1)
+	}
+
+	want := "http://i.imgur.com/LAobnLK.jpg"
+	if images[0].Link != want {
+		t.Errorf("Album.Images returned %+v, want %+v", images[0].Link, want)
+	}
+}
+
+func TestAlbumInfoMissingID(t *testing.T) {
+	if _, err := NewClient(nil, "", "").Album.Info(""); err == nil {
+		t.Errorf("Album.Info with empty id returned no error")
+	}
+}
@@ -0,0 +1,92 @@
+package imgur
+
+import (
+	"fmt"
+)
+
+// CommentService handles communication with the comment related
+// methods of the Imgur API.
+//
+// API docs: https://api.imgur.com/endpoints/comment
+type CommentService struct {
+	client *Client
+}
+
+// Comment sorts accepted by Gallery.
+const (
+	CommentSortBest = "best"
+	CommentSortTop  = "top"
+	CommentSortNew  = "new"
+)
+
+type Comment struct {
+	ID         int       `json:"id"`          // The ID for the comment
+	ImageID    string    `json:"image_id"`    // The ID of the image that the comment is for
+	Comment    string    `json:"comment"`     // The comment itself.
+	Author     string    `json:"author"`      // Username of the author of the comment
+	AuthorID   int       `json:"author_id"`   // The account ID for the author
+	OnAlbum    bool      `json:"on_album"`    // If this comment was done to an album
+	AlbumCover string    `json:"album_cover"` // The ID of the album cover image, this is what should be displayed for album comments
+	Ups        int       `json:"ups"`         // Number of upvotes for the comment
+	Downs      int       `json:"downs"`       // The number of downvotes for the comment
+	Points     float64   `json:"points"`      // the number of upvotes - downvotes
+	DateTime   int       `json:"datetime"`    // Timestamp of creation, epoch time
+	ParentID   int       `json:"parent_id"`   // If this is a reply, this will be the value of the comment_id for the caption this a reply for.
+	Deleted    bool      `json:"deleted"`     // Marked true if this caption has been deleted
+	Vote       string    `json:"vote"`        // The current user's vote on the comment: "up", "down" or empty
+	Children   []Comment `json:"children"`    // All of the replies for this comment. If there are no replies to the comment then this is an empty set.
+}
+
+// Info retrieves a single comment, including its replies.
+func (s *CommentService) Info(id int) (*Comment, error) {
+	url := fmt.Sprintf("comment/%d", id)
+	req, err := s.client.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type commentResponse struct {
+		Data *Comment
+		Result
+	}
+	response := &commentResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
+
+// Gallery retrieves the comment threads of a gallery item, sorted by
+// CommentSortBest, CommentSortTop or CommentSortNew.
+func (s *CommentService) Gallery(id, sort string) ([]Comment, error) {
+	if id == "" {
+		return nil, fmt.Errorf("gallery item id must be provided")
+	}
+
+	// optional    best | top | new - defaults to best
+	if sort == "" {
+		sort = CommentSortBest
+	}
+
+	url := fmt.Sprintf("gallery/%s/comments/%s", id, sort)
+	req, err := s.client.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type commentsResponse struct {
+		Data []Comment
+		Result
+	}
+	response := &commentsResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
@@ -0,0 +1,60 @@
+package imgur
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+const (
+	commentGalleryResponse = `{"data":[{"id":191797463,"image_id":"Wu0zw","comment":"I'd buy that for a dollar","author":"keanufan","author_id":1234,"on_album":true,"album_cover":"LAobnLK","ups":120,"downs":3,"points":117,"datetime":1390525000,"parent_id":0,"deleted":false,"vote":null,"platform":"desktop","children":[{"id":191797999,"image_id":"Wu0zw","comment":"woah","author":"ted","author_id":5678,"on_album":true,"album_cover":"LAobnLK","ups":10,"downs":0,"points":10,"datetime":1390525100,"parent_id":191797463,"deleted":false,"vote":null,"children":[]}]}],"success":true,"status":200}`
+
+	commentInfoResponse = `{"data":{"id":191797999,"image_id":"Wu0zw","comment":"woah","author":"ted","author_id":5678,"ups":10,"downs":0,"points":10,"datetime":1390525100,"parent_id":191797463,"deleted":false,"vote":null,"children":[]},"success":true,"status":200}`
+)
+
+func TestCommentGallery(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/gallery/Wu0zw/comments/best", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, commentGalleryResponse)
+	})
+
+	comments, err := client.Comment.Gallery("Wu0zw", "")
+	if err != nil {
+		t.Fatalf("Comment.Gallery returned error: %v", err)
+	}
+
+	if len(comments) != 1 {
+		t.Fatalf("Comment.Gallery returned %+v comments, want %+v", len(comments), 1)
+	}
+
+	if comments[0].Author != "keanufan" || comments[0].Points != 117 {
+		t.Errorf("Comment.Gallery returned %+v", comments[0])
+	}
+
+	if len(comments[0].Children) != 1 || comments[0].Children[0].ParentID != comments[0].ID {
+		t.Errorf("Comment.Gallery returned replies %+v", comments[0].Children)
+	}
+}
+
+func TestCommentInfo(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/comment/191797999", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, commentInfoResponse)
+	})
+
+	comment, err := client.Comment.Info(191797999)
+	if err != nil {
+		t.Fatalf("Comment.Info returned error: %v", err)
+	}
+
+	want := "woah"
+	if comment.Comment != want {
+		t.Errorf("Comment.Info returned %+v, want %+v", comment.Comment, want)
+	}
+}
@@ -1,7 +1,9 @@
 package imgur
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	// "log"
 )
 
@@ -13,30 +15,129 @@ type GalleryService struct {
 	client *Client
 }
 
+// Tag is a tag attached to gallery items.
+type Tag struct {
+	Name           string `json:"name"`            // Name of the tag
+	DisplayName    string `json:"display_name"`    // Name of the tag as it's shown to users
+	Followers      int    `json:"followers"`       // Number of followers for the tag
+	TotalItems     int    `json:"total_items"`     // Total number of gallery items tagged with the tag
+	Following      bool   `json:"following"`       // If the current user is following the tag
+	BackgroundHash string `json:"background_hash"` // Image ID of the tag's background image
+	Description    string `json:"description"`     // Description of the tag
+}
+
+// GalleryInfo holds the fields the gallery adds to an image or album that
+// has been submitted to it.
+type GalleryInfo struct {
+	Vote           string `json:"vote"`            // The current user's vote on the item: "up", "down" or empty
+	Ups            int    `json:"ups"`             // Upvotes for the item
+	Downs          int    `json:"downs"`           // Number of downvotes for the item
+	Points         int    `json:"points"`          // Upvotes minus downvotes
+	Score          int    `json:"score"`           // Imgur popularity score
+	IsAlbum        bool   `json:"is_album"`        // If it's an album or not
+	CommentCount   int    `json:"comment_count"`   // Number of comments on the gallery item
+	Topic          string `json:"topic"`           // Topic of the gallery item
+	TopicID        int    `json:"topic_id"`        // Topic ID of the gallery item
+	Tags           []Tag  `json:"tags"`            // Tags describing the item
+	InMostViral    bool   `json:"in_most_viral"`   // Indicates if the item is in the most viral gallery
+	RedditComments string `json:"reddit_comments"` // OPTIONAL, the relative path to the reddit comments, for subreddit galleries
+}
+
+// GalleryImage is an image that has been submitted to the gallery.
+type GalleryImage struct {
+	Image
+	GalleryInfo
+}
+
+// GalleryAlbum is an album that has been submitted to the gallery.
+type GalleryAlbum struct {
+	Album
+	GalleryInfo
+}
+
+// GalleryItem is either a *GalleryImage or a *GalleryAlbum.
+type GalleryItem interface {
+	Gallery() *GalleryInfo
+}
+
+func (i *GalleryImage) Gallery() *GalleryInfo { return &i.GalleryInfo }
+func (a *GalleryAlbum) Gallery() *GalleryInfo { return &a.GalleryInfo }
+
+// GalleryItems decodes the mixed arrays of images and albums gallery
+// endpoints return into *GalleryImage and *GalleryAlbum elements.
+type GalleryItems []GalleryItem
+
+func (items *GalleryItems) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*items = make(GalleryItems, 0, len(raw))
+	for _, r := range raw {
+		item, err := decodeGalleryItem(r)
+		if err != nil {
+			return err
+		}
+		*items = append(*items, item)
+	}
+	return nil
+}
+
+// decodeGalleryItem decodes a single gallery image or album, telling them
+// apart by is_album.
+func decodeGalleryItem(data []byte) (GalleryItem, error) {
+	var kind struct {
+		IsAlbum bool `json:"is_album"`
+	}
+	if err := json.Unmarshal(data, &kind); err != nil {
+		return nil, err
+	}
+
+	var item GalleryItem = &GalleryImage{}
+	if kind.IsAlbum {
+		item = &GalleryAlbum{}
+	}
+	if err := json.Unmarshal(data, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
 // Many of the Gallery endpoints return mixtures of
 // GalleryImage and GalleryAlbum, and these two structs
 // share many elements, so they are combined into a single struct.
-// Test the IsAlbum field to determine which type an instance is.
+// Test the IsAlbum field to determine which type an instance is,
+// or use Item to get the equivalent GalleryImage or GalleryAlbum.
 type GalleryImageAlbum struct {
 	// Common fields
-	ID           string `json:"id,omitempty"`
-	Title        string `json:"title,omitempty"`
-	Description  string `json:"description,omitempty"`
-	DateTime     int    `json:"datetime,omitempty"`
-	Views        int    `json:"views,omitempty"`
-	Vote         string `json:"vote,omitempty"`
-	Section      string `json:"section,omitempty"`
-	AccountUrl   string `json:"account_url,omitempty"`
-	Ups          int    `json:"ups,omitempty"`
-	Downs        int    `json:"downs,omitempty"`
-	Score        int    `json:"score,omitempty"`
-	Link         string `json:"link,omitempty"`
-	IsAlbum      bool   `json:"is_album,omitempty"`
-	Nsfw         bool   `json:"nsfw,omitempty"`
-	CommentCount int    `json:"comment_count,omitempty"`
+	ID             string `json:"id,omitempty"`
+	Title          string `json:"title,omitempty"`
+	Description    string `json:"description,omitempty"`
+	DateTime       int    `json:"datetime,omitempty"`
+	Views          int    `json:"views,omitempty"`
+	Vote           string `json:"vote,omitempty"`
+	Favorite       bool   `json:"favorite,omitempty"`
+	Section        string `json:"section,omitempty"`
+	AccountUrl     string `json:"account_url,omitempty"`
+	AccountID      int    `json:"account_id,omitempty"`
+	Ups            int    `json:"ups,omitempty"`
+	Downs          int    `json:"downs,omitempty"`
+	Points         int    `json:"points,omitempty"`
+	Score          int    `json:"score,omitempty"`
+	Link           string `json:"link,omitempty"`
+	IsAlbum        bool   `json:"is_album,omitempty"`
+	Nsfw           bool   `json:"nsfw,omitempty"`
+	CommentCount   int    `json:"comment_count,omitempty"`
+	Topic          string `json:"topic,omitempty"`
+	TopicID        int    `json:"topic_id,omitempty"`
+	Tags           []Tag  `json:"tags,omitempty"`
+	InMostViral    bool   `json:"in_most_viral,omitempty"`
+	InGallery      bool   `json:"in_gallery,omitempty"`
+	RedditComments string `json:"reddit_comments,omitempty"`
 
 	// Image only fields
-	Bandwidth  int    `json:"bandwidth,omitempty"`
+	Bandwidth  int64  `json:"bandwidth,omitempty"`
 	DeleteHash string `json:"deletehash,omitempty"`
 	Animated   bool   `json:"animated,omitempty"`
 	MimeType   string `json:"type,omitempty"`
@@ -44,7 +145,11 @@ type GalleryImageAlbum struct {
 	Height     int    `json:"height,omitempty"`
 	Size       int    `json:"size,omitempty"`
 	Gifv       string `json:"gifv,omitempty"`
+	Mp4        string `json:"mp4,omitempty"`
+	Mp4Size    int    `json:"mp4_size,omitempty"`
 	Webm       string `json:"webm,omitempty"`
+	Looping    bool   `json:"looping,omitempty"`
+	HasSound   bool   `json:"has_sound,omitempty"`
 
 	// Album only fields
 	Cover       string  `json:"cover,omitempty"`
@@ -56,43 +161,127 @@ type GalleryImageAlbum struct {
 	Images      []Image `json:"images,omitempty"`
 }
 
+// Item returns g as a *GalleryImage or *GalleryAlbum.
+func (g *GalleryImageAlbum) Item() GalleryItem {
+	info := GalleryInfo{
+		Vote:           g.Vote,
+		Ups:            g.Ups,
+		Downs:          g.Downs,
+		Points:         g.Points,
+		Score:          g.Score,
+		IsAlbum:        g.IsAlbum,
+		CommentCount:   g.CommentCount,
+		Topic:          g.Topic,
+		TopicID:        g.TopicID,
+		Tags:           g.Tags,
+		InMostViral:    g.InMostViral,
+		RedditComments: g.RedditComments,
+	}
+
+	if g.IsAlbum {
+		return &GalleryAlbum{
+			Album: Album{
+				ID:          g.ID,
+				Title:       g.Title,
+				Description: g.Description,
+				DateTime:    g.DateTime,
+				Cover:       g.Cover,
+				CoverWidth:  g.CoverWidth,
+				CoverHeight: g.CoverHeight,
+				AccountUrl:  g.AccountUrl,
+				AccountID:   g.AccountID,
+				Privacy:     g.Privacy,
+				Layout:      g.Layout,
+				Views:       g.Views,
+				Link:        g.Link,
+				Nsfw:        g.Nsfw,
+				Section:     g.Section,
+				DeleteHash:  g.DeleteHash,
+				Favorite:    g.Favorite,
+				InGallery:   g.InGallery,
+				ImagesCount: g.ImagesCount,
+				Images:      g.Images,
+			},
+			GalleryInfo: info,
+		}
+	}
+
+	return &GalleryImage{
+		Image: Image{
+			Id:          g.ID,
+			Title:       g.Title,
+			Description: g.Description,
+			DateTime:    g.DateTime,
+			MimeType:    g.MimeType,
+			Animated:    g.Animated,
+			Width:       g.Width,
+			Height:      g.Height,
+			Size:        g.Size,
+			Views:       g.Views,
+			Bandwidth:   g.Bandwidth,
+			DeleteHash:  g.DeleteHash,
+			Section:     g.Section,
+			Link:        g.Link,
+			Gifv:        g.Gifv,
+			Mp4:         g.Mp4,
+			Mp4Size:     g.Mp4Size,
+			Webm:        g.Webm,
+			Looping:     g.Looping,
+			HasSound:    g.HasSound,
+			Favorite:    g.Favorite,
+			Nsfw:        g.Nsfw,
+			InGallery:   g.InGallery,
+			AccountUrl:  g.AccountUrl,
+			AccountID:   g.AccountID,
+		},
+		GalleryInfo: info,
+	}
+}
+
 type galleryImageAlbumResult struct {
 	Data    []GalleryImageAlbum
 	Status  int
 	Success bool
 }
 
-// Return a gallery of the specified section, sort, window page, etc.
-func (s *GalleryService) gallery(route, sort, window, paramStr string, page int) ([]GalleryImageAlbum, error) {
+// galleryURL builds the URL of a gallery of the specified section, sort,
+// window, page, etc.
+func galleryURL(route, sort, window, paramStr string, page int) (string, error) {
 	if page < 0 {
 		page = 0
 	}
 
-	response := &galleryImageAlbumResult{}
-
 	if route == "" {
-		return response.Data, fmt.Errorf("route must be provided")
+		return "", fmt.Errorf("route must be provided")
 	}
 
-	url := "gallery/" + route
+	if sort == "" {
+		return "", fmt.Errorf("sort must be provided to gallery() method")
+	}
 
-	if sort != "" {
-		url = url + "/" + sort
-		if window != "" {
-			url = url + "/" + window
-		}
-		// Avoiding pulling in all of strconv
-		url = url + fmt.Sprintf("/%d", page)
-	} else {
-		return response.Data, fmt.Errorf("sort must be provided to gallery() method")
-		if window != "" {
-		}
+	url := "gallery/" + route + "/" + sort
+	if window != "" {
+		url = url + "/" + window
 	}
+	// Avoiding pulling in all of strconv
+	url = url + fmt.Sprintf("/%d", page)
 
 	if paramStr != "" {
 		url = url + paramStr
 	}
 
+	return url, nil
+}
+
+// Return a gallery of the specified section, sort, window page, etc.
+func (s *GalleryService) gallery(route, sort, window, paramStr string, page int) ([]GalleryImageAlbum, error) {
+	response := &galleryImageAlbumResult{}
+
+	url, err := galleryURL(route, sort, window, paramStr, page)
+	if err != nil {
+		return response.Data, err
+	}
+
 	req, err := s.client.NewRequest("GET", url, nil)
 	if err != nil {
 		return response.Data, err
@@ -137,7 +326,7 @@ func (s *GalleryService) Subreddit(subreddit, sort, window string, page int) ([]
 		window = "week"
 	}
 
-	route := fmt.Sprintf("r/%s", subreddit)
+	route := "r/" + url.PathEscape(subreddit)
 
 	return s.gallery(route, sort, window, "", page)
 }
@@ -163,7 +352,7 @@ func (s *GalleryService) Search(q string, sort string, page int) ([]GalleryImage
 		sort = "time"
 	}
 
-	searchQuery := fmt.Sprintf("?q=%s", q)
+	searchQuery := "?q=" + url.QueryEscape(q)
 
 	return s.gallery("search", sort, "", searchQuery, page)
 }
@@ -191,3 +380,200 @@ func (s *GalleryService) Random(page int) ([]GalleryImageAlbum, error) {
 
 	return response.Data, nil
 }
+
+// Item returns a single image or album from the gallery, as a
+// *GalleryImage or *GalleryAlbum.
+func (s *GalleryService) Item(id string) (GalleryItem, error) {
+	if id == "" {
+		return nil, fmt.Errorf("gallery item id must be provided")
+	}
+
+	req, err := s.client.NewRequest("GET", "gallery/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type itemResponse struct {
+		Data json.RawMessage
+		Result
+	}
+	response := &itemResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeGalleryItem(response.Data)
+}
+
+// ItemTags returns the tags attached to a gallery item.
+func (s *GalleryService) ItemTags(id string) ([]Tag, error) {
+	if id == "" {
+		return nil, fmt.Errorf("gallery item id must be provided")
+	}
+
+	req, err := s.client.NewRequest("GET", "gallery/"+url.PathEscape(id)+"/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type tagsResponse struct {
+		Data struct {
+			Tags []Tag
+		}
+		Result
+	}
+	response := &tagsResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data.Tags, nil
+}
+
+// Votes holds the vote counts of a gallery item.
+type Votes struct {
+	Ups   int `json:"ups"`
+	Downs int `json:"downs"`
+}
+
+// ItemVotes returns the vote counts of a gallery item.
+func (s *GalleryService) ItemVotes(id string) (*Votes, error) {
+	if id == "" {
+		return nil, fmt.Errorf("gallery item id must be provided")
+	}
+
+	req, err := s.client.NewRequest("GET", "gallery/"+url.PathEscape(id)+"/votes", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type votesResponse struct {
+		Data *Votes
+		Result
+	}
+	response := &votesResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
+
+// TagGallery is a tag along with the gallery items carrying it.
+type TagGallery struct {
+	Tag
+	Items []GalleryImageAlbum `json:"items"`
+}
+
+// Tag returns the gallery of items carrying a tag.
+func (s *GalleryService) Tag(tag, sort, window string, page int) (*TagGallery, error) {
+	if tag == "" {
+		return nil, fmt.Errorf("tag must be provided")
+	}
+
+	// optional    viral | top | time - defaults to viral
+	if sort == "" {
+		sort = "viral"
+	}
+
+	// optional    day | week | month | year | all - defaults to week, only used by top
+	if window == "" {
+		window = "week"
+	}
+
+	route := "t/" + url.PathEscape(tag)
+	path, err := galleryURL(route, sort, window, "", page)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type tagResponse struct {
+		Data *TagGallery
+		Result
+	}
+	response := &tagResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
+
+// Topic is a gallery topic.
+type Topic struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Topics returns the default topics.
+func (s *GalleryService) Topics() ([]Topic, error) {
+	req, err := s.client.NewRequest("GET", "topics/defaults", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	type topicsResponse struct {
+		Data []Topic
+		Result
+	}
+	response := &topicsResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
+
+// TopicGallery returns the gallery items in a topic, identified by its ID
+// or URL-formatted name.
+func (s *GalleryService) TopicGallery(topic, sort, window string, page int) ([]GalleryImageAlbum, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("topic must be provided")
+	}
+
+	// optional    viral | top | time | rising - defaults to viral
+	if sort == "" {
+		sort = "viral"
+	}
+
+	// optional    day | week | month | year | all - defaults to week, only used by top
+	if window == "" {
+		window = "week"
+	}
+
+	if page < 0 {
+		page = 0
+	}
+
+	response := &galleryImageAlbumResult{}
+
+	path := fmt.Sprintf("topics/%s/%s/%s/%d", url.PathEscape(topic), sort, window, page)
+
+	req, err := s.client.NewRequest("GET", path, nil)
+	if err != nil {
+		return response.Data, err
+	}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
@@ -1,6 +1,7 @@
 package imgur
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
@@ -42,6 +43,25 @@ func TestGallerySearch(t *testing.T) {
 	}
 }
 
+func TestGallerySearchEscapesQuery(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/gallery/search/top/0", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Query().Get("q"), "funny cats & dogs?"; got != want {
+			t.Errorf("Request q = %v, want %v", got, want)
+		}
+		if got := len(r.URL.Query()); got != 1 {
+			t.Errorf("Request has %v query parameters, want 1", got)
+		}
+		fmt.Fprint(w, gallerySearchResponse)
+	})
+
+	if _, err := client.Gallery.Search("funny cats & dogs?", "top", 0); err != nil {
+		t.Errorf("Gallery.Search returned error: %v", err)
+	}
+}
+
 func TestGalleryRandom(t *testing.T) {
 	imgurTestSetup()
 	defer imgurTestTeardown()
@@ -137,3 +157,212 @@ func TestGalleryMemes(t *testing.T) {
 		t.Errorf("Gallery.Memes returned %+v, want %+v", albumImgs[0].ID, want)
 	}
 }
+
+const galleryMixedResponse = `[{"id":"Wu0zw","title":"Sad Keanu is Now an Action Figure!","description":null,"datetime":1390524925,"cover":"LAobnLK","views":770,"vote":null,"favorite":false,"nsfw":null,"section":null,"account_url":"literallyannperkins","account_id":null,"ups":27,"downs":2,"points":25,"score":25,"is_album":true,"topic":null,"topic_id":null,"tags":null,"in_most_viral":false,"images_count":2},{"id":"zHQ2rzI","title":"Of course, but maybe...","description":null,"datetime":1404778199,"type":"image\/gif","animated":true,"width":610,"height":679,"size":395612,"views":22,"bandwidth":8703464000,"vote":"up","favorite":true,"nsfw":false,"section":"funny","account_url":"kJerAFK","account_id":3765705,"link":"http:\/\/i.imgur.com\/zHQ2rzI.gif","gifv":"http:\/\/i.imgur.com\/zHQ2rzI.gifv","mp4":"http:\/\/i.imgur.com\/zHQ2rzI.mp4","mp4_size":112233,"looping":true,"is_album":false,"in_most_viral":true,"in_gallery":true,"topic":"Funny","topic_id":2,"tags":[{"name":"funny","display_name":"Funny","followers":100,"total_items":1000,"following":false,"background_hash":"ZPPgHHk","description":"lol"}]}]`
+
+func TestGalleryItemsDecode(t *testing.T) {
+	var items GalleryItems
+	if err := json.Unmarshal([]byte(galleryMixedResponse), &items); err != nil {
+		t.Fatalf("GalleryItems decode returned error: %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("GalleryItems decoded %v items, want %v", len(items), 2)
+	}
+
+	album, ok := items[0].(*GalleryAlbum)
+	if !ok {
+		t.Fatalf("GalleryItems[0] is %T, want *GalleryAlbum", items[0])
+	}
+	if album.Cover != "LAobnLK" || album.Vote != "" || album.AccountID != 0 || album.Points != 25 {
+		t.Errorf("GalleryItems[0] decoded as %+v", album)
+	}
+
+	img, ok := items[1].(*GalleryImage)
+	if !ok {
+		t.Fatalf("GalleryItems[1] is %T, want *GalleryImage", items[1])
+	}
+	if img.Mp4 != "http://i.imgur.com/zHQ2rzI.mp4" || !img.InMostViral || img.AccountID != 3765705 || img.Bandwidth != 8703464000 {
+		t.Errorf("GalleryItems[1] decoded as %+v", img)
+	}
+	if len(img.Tags) != 1 || img.Tags[0].Name != "funny" || img.Gallery().Topic != "Funny" {
+		t.Errorf("GalleryItems[1] decoded tags %+v, topic %q", img.Tags, img.Topic)
+	}
+}
+
+func TestGalleryImageAlbumItem(t *testing.T) {
+	var combined []GalleryImageAlbum
+	if err := json.Unmarshal([]byte(galleryMixedResponse), &combined); err != nil {
+		t.Fatalf("GalleryImageAlbum decode returned error: %v", err)
+	}
+
+	if album, ok := combined[0].Item().(*GalleryAlbum); !ok || album.ID != "Wu0zw" || album.ImagesCount != 2 {
+		t.Errorf("GalleryImageAlbum.Item returned %+v, want album Wu0zw", combined[0].Item())
+	}
+
+	img, ok := combined[1].Item().(*GalleryImage)
+	if !ok || img.Id != "zHQ2rzI" || img.MimeType != "image/gif" || len(img.Tags) != 1 {
+		t.Errorf("GalleryImageAlbum.Item returned %+v, want image zHQ2rzI", combined[1].Item())
+	}
+}
+
+const (
+	galleryItemResponse = `{"data":{"id":"Wu0zw","title":"Sad Keanu is Now an Action Figure!","description":null,"datetime":1390524925,"cover":"LAobnLK","account_url":"literallyannperkins","views":770,"link":"http:\/\/imgur.com\/a\/Wu0zw","ups":27,"downs":2,"points":25,"score":25,"is_album":true,"vote":null,"comment_count":12,"topic":"Funny","topic_id":2,"images_count":2},"success":true,"status":200}`
+
+	galleryItemTagsResponse = `{"data":{"tags":[{"name":"keanu","display_name":"keanu","followers":10,"total_items":250,"following":false,"background_hash":"abc123","description":null}]},"success":true,"status":200}`
+
+	galleryItemVotesResponse = `{"data":{"ups":27,"downs":2},"success":true,"status":200}`
+
+	galleryTagResponse = `{"data":{"name":"cats","display_name":"cats","followers":1200,"total_items":50000,"following":false,"background_hash":"Yq2Oc1w","description":"cats being cats","items":[{"id":"1YUpmrH","title":"Cat","is_album":false,"link":"http:\/\/i.imgur.com\/1YUpmrH.jpg","type":"image\/jpeg","width":639,"height":852}]},"success":true,"status":200}`
+
+	galleryTopicsResponse = `{"data":[{"id":2,"name":"Funny","description":"if it makes you laugh, you can find it here."}],"success":true,"status":200}`
+)
+
+func TestGalleryItem(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/gallery/Wu0zw", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, galleryItemResponse)
+	})
+
+	item, err := client.Gallery.Item("Wu0zw")
+	if err != nil {
+		t.Fatalf("Gallery.Item returned error: %v", err)
+	}
+
+	album, ok := item.(*GalleryAlbum)
+	if !ok {
+		t.Fatalf("Gallery.Item returned %T, want *GalleryAlbum", item)
+	}
+
+	if album.AccountUrl != "literallyannperkins" || album.CommentCount != 12 || album.Topic != "Funny" {
+		t.Errorf("Gallery.Item returned %+v", album)
+	}
+}
+
+func TestGalleryItemTags(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/gallery/Wu0zw/tags", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, galleryItemTagsResponse)
+	})
+
+	tags, err := client.Gallery.ItemTags("Wu0zw")
+	if err != nil {
+		t.Fatalf("Gallery.ItemTags returned error: %v", err)
+	}
+
+	if len(tags) != 1 || tags[0].Name != "keanu" {
+		t.Errorf("Gallery.ItemTags returned %+v, want keanu", tags)
+	}
+}
+
+func TestGalleryItemVotes(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/gallery/Wu0zw/votes", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, galleryItemVotesResponse)
+	})
+
+	votes, err := client.Gallery.ItemVotes("Wu0zw")
+	if err != nil {
+		t.Fatalf("Gallery.ItemVotes returned error: %v", err)
+	}
+
+	if votes.Ups != 27 || votes.Downs != 2 {
+		t.Errorf("Gallery.ItemVotes returned %+v, want 27 ups and 2 downs", votes)
+	}
+}
+
+func TestGalleryTag(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/gallery/t/cats/viral/week/0", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, galleryTagResponse)
+	})
+
+	tag, err := client.Gallery.Tag("cats", "", "", 0)
+	if err != nil {
+		t.Fatalf("Gallery.Tag returned error: %v", err)
+	}
+
+	if tag.TotalItems != 50000 || len(tag.Items) != 1 {
+		t.Fatalf("Gallery.Tag returned %+v", tag)
+	}
+
+	want := "1YUpmrH"
+	if tag.Items[0].ID != want {
+		t.Errorf("Gallery.Tag returned %+v, want %+v", tag.Items[0].ID, want)
+	}
+}
+
+func TestGalleryEscapesPathSegments(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	var path, query string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.EscapedPath(), r.URL.RawQuery
+		fmt.Fprint(w, `{"data":null,"success":true,"status":200}`)
+	})
+
+	const in = "pics?sort=top/x"
+	for _, tt := range []struct {
+		name string
+		call func()
+		want string
+	}{
+		{"Item", func() { client.Gallery.Item(in) }, "/gallery/pics%3Fsort=top%2Fx"},
+		{"ItemTags", func() { client.Gallery.ItemTags(in) }, "/gallery/pics%3Fsort=top%2Fx/tags"},
+		{"ItemVotes", func() { client.Gallery.ItemVotes(in) }, "/gallery/pics%3Fsort=top%2Fx/votes"},
+		{"Subreddit", func() { client.Gallery.Subreddit(in, "", "", 0) }, "/gallery/r/pics%3Fsort=top%2Fx/time/week/0"},
+		{"Tag", func() { client.Gallery.Tag(in, "", "", 0) }, "/gallery/t/pics%3Fsort=top%2Fx/viral/week/0"},
+		{"TopicGallery", func() { client.Gallery.TopicGallery(in, "", "", 0) }, "/topics/pics%3Fsort=top%2Fx/viral/week/0"},
+	} {
+		path, query = "", ""
+		tt.call()
+		if path != tt.want || query != "" {
+			t.Errorf("Gallery.%v(%q) requested %v?%v, want %v", tt.name, in, path, query, tt.want)
+		}
+	}
+}
+
+func TestGalleryTopics(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/topics/defaults", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, galleryTopicsResponse)
+	})
+	mux.HandleFunc("/topics/Funny/viral/week/0", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, galleryMainResponse)
+	})
+
+	topics, err := client.Gallery.Topics()
+	if err != nil {
+		t.Fatalf("Gallery.Topics returned error: %v", err)
+	}
+	if len(topics) != 1 || topics[0].Name != "Funny" {
+		t.Fatalf("Gallery.Topics returned %+v, want Funny", topics)
+	}
+
+	albumImgs, err := client.Gallery.TopicGallery(topics[0].Name, "", "", 0)
+	if err != nil {
+		t.Fatalf("Gallery.TopicGallery returned error: %v", err)
+	}
+
+	want := "Lh6MuPp"
+	if len(albumImgs) == 0 || albumImgs[0].ID != want {
+		t.Errorf("Gallery.TopicGallery returned %+v, want %+v", albumImgs, want)
+	}
+}
@@ -1,7 +1,13 @@
 package imgur
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"path"
+	"strings"
 )
 
 const (
@@ -13,6 +19,41 @@ const (
 	ThumbHuge        = "h" // 1024x1024
 )
 
+// thumbSizes are the bounding box sizes, in pixels, of the Thumb* sizes.
+// The square sizes are cropped, the others keep the image's proportions.
+var thumbSizes = map[string]int{
+	ThumbSmallSquare: 90,
+	ThumbBigSquare:   160,
+	ThumbSmall:       160,
+	ThumbMedium:      320,
+	ThumbLarge:       640,
+	ThumbHuge:        1024,
+}
+
+// ThumbSize returns the width and height of the box a Thumb* size fits
+// within, or 0 for an unknown size.
+func ThumbSize(size string) int {
+	return thumbSizes[size]
+}
+
+// ThumbnailURL returns the direct link to a thumbnail of the image at link
+// in one of the Thumb* sizes.  link is an image's direct link, as in
+// Image.Link; an empty size returns it unchanged.
+func ThumbnailURL(link, size string) string {
+	if size == "" {
+		return link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	ext := path.Ext(u.Path)
+	u.Path = strings.TrimSuffix(u.Path, ext) + size + ext
+	return u.String()
+}
+
 // ImageService handles communication with the image related
 // methods of the Imgur API.
 //
@@ -21,26 +62,42 @@ type ImageService struct {
 	client *Client
 }
 
+// Image is an image as returned by the image and album endpoints.  Imgur
+// sends null for many fields it has no value for (an untitled image's
+// title, an anonymous uploader's account); those decode to the zero value.
 type Image struct {
-	Id          string // The ID for the image
-	Title       string // The title of the image.
-	Description string // Description of the image.
-	DateTime    int    // Time inserted into the gallery, epoch time
-	MimeType    string // Image MIME type.
-	Animated    bool   // is the image animated
-	Width       int    // The width of the image in pixels
-	Height      int    // The height of the image in pixels
-	Size        int    // The size of the image in bytes
-	Views       int    // The number of image views
-	Bandwidth   int    // Bandwidth consumed by the image in bytes
-	DeleteHash  string // OPTIONAL, the deletehash, if you're logged in as the image owner
-	Section     string // If the image has been categorized by our backend then this will contain the section the image belongs in. (funny, cats, adviceanimals, wtf, etc)
-	Link        string // The direct link to the the image
-	Nsfw        bool   // Is the link safe for work
-	Gifv        string
-	Mp4         string
-	Webm        string
-	Looping     bool
+	Id          string `json:"id"`          // The ID for the image
+	Title       string `json:"title"`       // The title of the image.
+	Description string `json:"description"` // Description of the image.
+	DateTime    int    `json:"datetime"`    // Time inserted into the gallery, epoch time
+	MimeType    string `json:"type"`        // Image MIME type.
+	Animated    bool   `json:"animated"`    // is the image animated
+	Width       int    `json:"width"`       // The width of the image in pixels
+	Height      int    `json:"height"`      // The height of the image in pixels
+	Size        int    `json:"size"`        // The size of the image in bytes
+	Views       int    `json:"views"`       // The number of image views
+	Bandwidth   int64  `json:"bandwidth"`   // Bandwidth consumed by the image in bytes
+	DeleteHash  string `json:"deletehash"`  // OPTIONAL, the deletehash, if you're logged in as the image owner
+	Name        string `json:"name"`        // OPTIONAL, the original filename, if you're logged in as the image owner
+	Section     string `json:"section"`     // If the image has been categorized by our backend then this will contain the section the image belongs in. (funny, cats, adviceanimals, wtf, etc)
+	Link        string `json:"link"`        // The direct link to the the image
+	Gifv        string `json:"gifv"`        // OPTIONAL, the .gifv link, only available if the image is animated
+	Mp4         string `json:"mp4"`         // OPTIONAL, the direct link to the .mp4, only available if the image is animated
+	Mp4Size     int    `json:"mp4_size"`    // OPTIONAL, the Content-Length of the .mp4, only available if the image is animated
+	Webm        string `json:"webm"`        // OPTIONAL, the direct link to the .webm, only available if the image is animated
+	Looping     bool   `json:"looping"`     // OPTIONAL, whether the image has a looping animation
+	HasSound    bool   `json:"has_sound"`   // Whether the animation has an audio track
+	Favorite    bool   `json:"favorite"`    // Indicates if the current user favorited the image
+	Nsfw        bool   `json:"nsfw"`        // Is the link safe for work
+	InGallery   bool   `json:"in_gallery"`  // True if the image has been submitted to the gallery
+	AccountUrl  string `json:"account_url"` // The username of the uploader, empty if anonymous
+	AccountID   int    `json:"account_id"`  // The account ID of the uploader, zero if anonymous
+}
+
+// Thumbnail returns the direct link to a thumbnail of the image in one of
+// the Thumb* sizes.
+func (i *Image) Thumbnail(size string) string {
+	return ThumbnailURL(i.Link, size)
 }
 
 // Info retrieves information about an image.
@@ -64,3 +121,144 @@ func (s *ImageService) Info(id string) (*Image, error) {
 
 	return response.Data, nil
 }
+
+// UploadOptions specifies the optional metadata attached to an image
+// when it is uploaded.
+type UploadOptions struct {
+	Album       string // The id of the album to add the image to; the deletehash for anonymous albums
+	Name        string // The name of the file
+	Title       string // The title of the image
+	Description string // The description of the image
+}
+
+// values returns the form fields for opt, which may be nil.
+func (opt *UploadOptions) values() url.Values {
+	v := url.Values{}
+	if opt == nil {
+		return v
+	}
+	if opt.Album != "" {
+		v.Set("album", opt.Album)
+	}
+	if opt.Name != "" {
+		v.Set("name", opt.Name)
+	}
+	if opt.Title != "" {
+		v.Set("title", opt.Title)
+	}
+	if opt.Description != "" {
+		v.Set("description", opt.Description)
+	}
+	return v
+}
+
+// Upload uploads the image data read from r as a multipart file.
+//
+// Without an access token the image is uploaded anonymously; keep the
+// returned DeleteHash, as it's the only way to update or delete it later.
+// With an access token the image belongs to the authorized account.
+func (s *ImageService) Upload(r io.Reader, filename string, opt *UploadOptions) (*Image, error) {
+	if filename == "" {
+		filename = "image"
+	}
+
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+
+	for key, values := range opt.values() {
+		for _, value := range values {
+			if err := w.WriteField(key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := w.WriteField("type", "file"); err != nil {
+		return nil, err
+	}
+
+	part, err := w.CreateFormFile("image", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return s.upload(body, w.FormDataContentType())
+}
+
+// UploadBase64 uploads a base64 encoded image.
+func (s *ImageService) UploadBase64(data string, opt *UploadOptions) (*Image, error) {
+	v := opt.values()
+	v.Set("image", data)
+	v.Set("type", "base64")
+	return s.upload(strings.NewReader(v.Encode()), formContentType)
+}
+
+// UploadURL asks Imgur to fetch and upload the image found at link.
+func (s *ImageService) UploadURL(link string, opt *UploadOptions) (*Image, error) {
+	v := opt.values()
+	v.Set("image", link)
+	v.Set("type", "URL")
+	return s.upload(strings.NewReader(v.Encode()), formContentType)
+}
+
+func (s *ImageService) upload(body io.Reader, contentType string) (*Image, error) {
+	req, err := s.client.NewUploadRequest("POST", "image", body, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	type imgResponse struct {
+		Data *Image
+		Result
+	}
+	response := &imgResponse{}
+
+	_, err = s.client.Do(req, response)
+	if err != nil {
+		return response.Data, err
+	}
+
+	return response.Data, nil
+}
+
+// Update updates the title and description of an image. Anonymous images
+// are identified by their DeleteHash, images owned by the authorized
+// account by their Id.
+func (s *ImageService) Update(idOrDeleteHash, title, description string) (bool, error) {
+	if idOrDeleteHash == "" {
+		return false, fmt.Errorf("image id or deletehash must be provided")
+	}
+
+	v := url.Values{}
+	v.Set("title", title)
+	v.Set("description", description)
+
+	url := fmt.Sprintf("image/%s", idOrDeleteHash)
+	req, err := s.client.NewUploadRequest("POST", url, strings.NewReader(v.Encode()), formContentType)
+	if err != nil {
+		return false, err
+	}
+
+	return s.client.doBool(req)
+}
+
+// Delete deletes an image. Anonymous images are identified by their
+// DeleteHash, images owned by the authorized account by their Id.
+func (s *ImageService) Delete(idOrDeleteHash string) (bool, error) {
+	if idOrDeleteHash == "" {
+		return false, fmt.Errorf("image id or deletehash must be provided")
+	}
+
+	url := fmt.Sprintf("image/%s", idOrDeleteHash)
+	req, err := s.client.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return s.client.doBool(req)
+}
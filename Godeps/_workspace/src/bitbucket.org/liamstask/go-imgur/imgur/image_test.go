@@ -0,0 +1,188 @@
+package imgur
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	imageUploadResponse = `{"data":{"id":"OB74hEa","title":"dickbutt","description":null,"datetime":1404780000,"type":"image\/png","animated":false,"width":640,"height":480,"size":123456,"views":0,"bandwidth":0,"deletehash":"x70po4w7BVvSUzZ","name":"","link":"http:\/\/i.imgur.com\/OB74hEa.png"},"success":true,"status":200}`
+
+	imageBoolResponse = `{"data":true,"success":true,"status":200}`
+)
+
+func TestImageUpload(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		testHeader(t, r, "Authorization", "Client-ID clientID")
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("ParseMultipartForm returned error: %v", err)
+		}
+		if got := r.FormValue("title"); got != "dickbutt" {
+			t.Errorf("Request title = %v, want %v", got, "dickbutt")
+		}
+		if got := r.FormValue("type"); got != "file" {
+			t.Errorf("Request type = %v, want %v", got, "file")
+		}
+
+		f, hdr, err := r.FormFile("image")
+		if err != nil {
+			t.Fatalf("FormFile returned error: %v", err)
+		}
+		defer f.Close()
+		data, _ := ioutil.ReadAll(f)
+		if string(data) != "PNG bytes" || hdr.Filename != "dickbutt.png" {
+			t.Errorf("Request image = %q (%v), want %q (%v)", data, hdr.Filename, "PNG bytes", "dickbutt.png")
+		}
+
+		fmt.Fprint(w, imageUploadResponse)
+	})
+
+	img, err := client.Image.Upload(strings.NewReader("PNG bytes"), "dickbutt.png", &UploadOptions{Title: "dickbutt"})
+	if err != nil {
+		t.Fatalf("Image.Upload returned error: %v", err)
+	}
+
+	want := "x70po4w7BVvSUzZ"
+	if img.DeleteHash != want {
+		t.Errorf("Image.Upload returned deletehash %+v, want %+v", img.DeleteHash, want)
+	}
+}
+
+func TestImageUploadURL(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if got := r.FormValue("type"); got != "URL" {
+			t.Errorf("Request type = %v, want %v", got, "URL")
+		}
+		if got := r.FormValue("image"); got != "http://example.com/a.png" {
+			t.Errorf("Request image = %v, want %v", got, "http://example.com/a.png")
+		}
+		fmt.Fprint(w, imageUploadResponse)
+	})
+
+	img, err := client.Image.UploadURL("http://example.com/a.png", nil)
+	if err != nil {
+		t.Fatalf("Image.UploadURL returned error: %v", err)
+	}
+
+	want := "http://i.imgur.com/OB74hEa.png"
+	if img.Link != want {
+		t.Errorf("Image.UploadURL returned %+v, want %+v", img.Link, want)
+	}
+}
+
+func TestImageUploadBase64(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if got := r.FormValue("type"); got != "base64" {
+			t.Errorf("Request type = %v, want %v", got, "base64")
+		}
+		if got := r.FormValue("image"); got != "UE5HIGJ5dGVz" {
+			t.Errorf("Request image = %v, want %v", got, "UE5HIGJ5dGVz")
+		}
+		fmt.Fprint(w, imageUploadResponse)
+	})
+
+	if _, err := client.Image.UploadBase64("UE5HIGJ5dGVz", nil); err != nil {
+		t.Fatalf("Image.UploadBase64 returned error: %v", err)
+	}
+}
+
+func TestImageUploadAuthenticated(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer token")
+		fmt.Fprint(w, imageUploadResponse)
+	})
+
+	client.SetAccessToken("token")
+	if _, err := client.Image.UploadURL("http://example.com/a.png", nil); err != nil {
+		t.Fatalf("Image.UploadURL returned error: %v", err)
+	}
+}
+
+func TestImageUpdate(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/image/x70po4w7BVvSUzZ", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if got := r.FormValue("description"); got != "butts" {
+			t.Errorf("Request description = %v, want %v", got, "butts")
+		}
+		fmt.Fprint(w, imageBoolResponse)
+	})
+
+	ok, err := client.Image.Update("x70po4w7BVvSUzZ", "dickbutt", "butts")
+	if err != nil {
+		t.Fatalf("Image.Update returned error: %v", err)
+	}
+	if !ok {
+		t.Errorf("Image.Update returned %v, want %v", ok, true)
+	}
+}
+
+func TestImageDelete(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/image/x70po4w7BVvSUzZ", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		fmt.Fprint(w, imageBoolResponse)
+	})
+
+	ok, err := client.Image.Delete("x70po4w7BVvSUzZ")
+	if err != nil {
+		t.Fatalf("Image.Delete returned error: %v", err)
+	}
+	if !ok {
+		t.Errorf("Image.Delete returned %v, want %v", ok, true)
+	}
+}
+
+func TestImageDeleteError(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/image/nope", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"data":{"error":"Permission denied","request":"\/3\/image\/nope","method":"DELETE"},"success":false,"status":403}`)
+	})
+
+	if _, err := client.Image.Delete("nope"); err == nil {
+		t.Errorf("Image.Delete returned no error for a 403")
+	}
+}
+
+func TestThumbnailURL(t *testing.T) {
+	img := &Image{Link: "http://i.imgur.com/OB74hEa.png"}
+
+	want := "http://i.imgur.com/OB74hEam.png"
+	if got := img.Thumbnail(ThumbMedium); got != want {
+		t.Errorf("Image.Thumbnail returned %v, want %v", got, want)
+	}
+
+	if got := ThumbnailURL(img.Link, ""); got != img.Link {
+		t.Errorf("ThumbnailURL with no size returned %v, want %v", got, img.Link)
+	}
+
+	if got := ThumbSize(ThumbLarge); got != 640 {
+		t.Errorf("ThumbSize returned %v, want %v", got, 640)
+	}
+}
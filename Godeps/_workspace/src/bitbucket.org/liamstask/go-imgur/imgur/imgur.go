@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	// "log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -16,6 +18,9 @@ const (
 	userAgent      = "go-imgur/" + libraryVersion
 
 	defaultBaseURL = "https://api.imgur.com/3/"
+	defaultAuthURL = "https://api.imgur.com/oauth2/"
+
+	formContentType = "application/x-www-form-urlencoded"
 
 	hdrUserRateLimit       = "X-RateLimit-UserLimit"       // per user limit
 	hdrUserRateRemaining   = "X-RateLimit-UserRemaining"   // per user remaining
@@ -30,6 +35,9 @@ type Client struct {
 
 	BaseURL *url.URL
 
+	// Base URL for the OAuth2 authorize and token endpoints.
+	AuthURL *url.URL
+
 	// User agent used when communicating with the imgur API.
 	UserAgent string
 
@@ -40,11 +48,23 @@ type Client struct {
 	Rate Rate
 
 	// Services used for talking to different parts of the API.
+	Album   *AlbumService
+	Comment *CommentService
 	Gallery *GalleryService
 	Image   *ImageService
 
 	clientID     string
 	clientSecret string
+
+	// tokens holds the OAuth2 token requests are authorized with.  When
+	// nil, or empty, requests are made anonymously with the Client-ID.
+	// refreshing is set while a request refreshes the token, and after a
+	// failure the token isn't used again until retryRefresh.
+	tokenMu         sync.Mutex
+	tokens          TokenStore
+	refreshing      bool
+	refreshFailures int
+	retryRefresh    time.Time
 }
 
 type Result struct {
@@ -72,21 +92,25 @@ type Response struct {
 }
 
 // NewClient returns a new Imgur API client.  If a nil httpClient is
-// provided, http.DefaultClient will be used.  To use API methods which require
-// authentication, provide an http.Client that will perform the authentication
-// for you (such as that provided by the goauth2 library).
+// provided, http.DefaultClient will be used.  Requests are anonymous until
+// the client is given an OAuth2 token with SetTokenStore or SetAccessToken;
+// see AuthorizeURL for obtaining one.
 func NewClient(httpClient *http.Client, apiId, apiSecret string) *Client {
 	if httpClient == nil {
 		httpClient = http.DefaultClient
 	}
 	baseURL, _ := url.Parse(defaultBaseURL)
+	authURL, _ := url.Parse(defaultAuthURL)
 
 	c := &Client{client: httpClient,
 		BaseURL:      baseURL,
+		AuthURL:      authURL,
 		UserAgent:    userAgent,
-		clientID:     fmt.Sprintf("Client-ID %s", apiId),
+		clientID:     apiId,
 		clientSecret: apiSecret,
 	}
+	c.Album = &AlbumService{client: c}
+	c.Comment = &CommentService{client: c}
 	c.Gallery = &GalleryService{client: c}
 	c.Image = &ImageService{client: c}
 
@@ -120,7 +144,30 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 	}
 
 	req.Header.Add("User-Agent", c.UserAgent)
-	req.Header.Add("Authorization", c.clientID)
+	req.Header.Add("Authorization", c.authorization())
+	return req, nil
+}
+
+// NewUploadRequest creates an API request whose body is read from body as-is
+// rather than JSON encoded, such as a multipart image upload or a form.
+// A relative URL can be provided in urlStr, in which case it is resolved
+// relative to the BaseURL of the Client.
+func (c *Client) NewUploadRequest(method, urlStr string, body io.Reader, contentType string) (*http.Request, error) {
+	rel, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.BaseURL.ResolveReference(rel)
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+	req.Header.Add("User-Agent", c.UserAgent)
+	req.Header.Add("Authorization", c.authorization())
 	return req, nil
 }
 
@@ -151,6 +198,23 @@ func (c *Client) Do(req *http.Request, v interface{}) (*Response, error) {
 	return response, err
 }
 
+// doBool sends an API request whose response data is a plain boolean, as
+// returned by update and delete endpoints.
+func (c *Client) doBool(req *http.Request) (bool, error) {
+	type boolResponse struct {
+		Data bool
+		Result
+	}
+	response := &boolResponse{}
+
+	_, err := c.Do(req, response)
+	if err != nil {
+		return false, err
+	}
+
+	return response.Data, nil
+}
+
 // newResponse creats a new Response for the provided http.Response.
 func newResponse(r *http.Response) *Response {
 	resp := &Response{Response: r}
@@ -30,6 +30,7 @@ func imgurTestSetup() {
 	client = NewClient(nil, "clientID", "clientSecret")
 	url, _ := url.Parse(server.URL)
 	client.BaseURL = url
+	client.AuthURL, _ = url.Parse("oauth2/")
 	// client.UploadURL = url
 }
 
@@ -44,3 +45,10 @@ func testMethod(t *testing.T, r *http.Request, want string) {
 		t.Errorf("Request method = %v, want %v", r.Method, want)
 	}
 }
+
+// test that a request header is what we expect
+func testHeader(t *testing.T, r *http.Request, header string, want string) {
+	if got := r.Header.Get(header); want != got {
+		t.Errorf("Header.Get(%q) returned %q, want %q", header, got, want)
+	}
+}
@@ -0,0 +1,338 @@
+package imgur
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response types accepted by the authorize endpoint.
+const (
+	ResponseTypeCode  = "code"  // redirect back with an authorization code
+	ResponseTypeToken = "token" // redirect back with the tokens in the URL fragment
+	ResponseTypePin   = "pin"   // show the user a PIN to paste into the application
+)
+
+// expiryDelta is how long before its actual expiry a token is refreshed,
+// so requests already in flight don't race the deadline.
+const expiryDelta = 10 * time.Second
+
+// Token is an OAuth2 token issued by Imgur.
+//
+// API docs: https://api.imgur.com/oauth2
+type Token struct {
+	AccessToken     string    `json:"access_token"`
+	RefreshToken    string    `json:"refresh_token"`
+	TokenType       string    `json:"token_type"`
+	ExpiresIn       int       `json:"expires_in"` // Lifetime of the access token in seconds, as issued
+	Expiry          time.Time `json:"expiry"`     // When the access token expires; zero means never
+	AccountID       int       `json:"account_id"`
+	AccountUsername string    `json:"account_username"`
+}
+
+// Expired reports whether the access token is missing or about to expire.
+func (t *Token) Expired() bool {
+	if t.AccessToken == "" {
+		return true
+	}
+	if t.Expiry.IsZero() {
+		return false
+	}
+	return time.Now().Add(expiryDelta).After(t.Expiry)
+}
+
+// TokenStore persists the token a Client is authorized with.  Imgur rotates
+// refresh tokens, so SetToken is called with every newly issued token and
+// the store must keep the latest one.
+type TokenStore interface {
+	// Token returns the stored token, or nil if there isn't one.
+	Token() (*Token, error)
+	SetToken(*Token) error
+}
+
+// MemoryTokenStore is a TokenStore that only lives as long as the process.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token *Token
+}
+
+func (s *MemoryTokenStore) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, nil
+}
+
+func (s *MemoryTokenStore) SetToken(t *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = t
+	return nil
+}
+
+// FileTokenStore is a TokenStore that keeps the token as JSON in a file.
+type FileTokenStore struct {
+	Path string
+}
+
+func (s *FileTokenStore) Token() (*Token, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Token{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *FileTokenStore) SetToken(t *Token) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	// Write and rename so a crash can't leave a truncated token behind.
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// SetTokenStore makes the client authorize requests with the token held in
+// ts, refreshing it when it expires.  A nil store reverts to anonymous
+// requests.
+func (c *Client) SetTokenStore(ts TokenStore) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.tokens = ts
+	c.refreshFailures = 0
+	c.retryRefresh = time.Time{}
+}
+
+// SetAccessToken makes the client act on behalf of the account the access
+// token was issued to.  An empty token reverts to anonymous requests.
+func (c *Client) SetAccessToken(token string) {
+	if token == "" {
+		c.SetTokenStore(nil)
+		return
+	}
+	c.SetTokenStore(&MemoryTokenStore{token: &Token{AccessToken: token, TokenType: "bearer"}})
+}
+
+// AuthorizeURL returns the URL to send a user to so they can grant the
+// application access to their account.  responseType is one of
+// ResponseTypeCode, ResponseTypeToken or ResponseTypePin; state is passed
+// back unchanged to the application's callback.
+func (c *Client) AuthorizeURL(responseType, state string) string {
+	v := url.Values{}
+	v.Set("client_id", c.clientID)
+	v.Set("response_type", responseType)
+	if state != "" {
+		v.Set("state", state)
+	}
+
+	u := c.AuthURL.ResolveReference(&url.URL{Path: "authorize"})
+	u.RawQuery = v.Encode()
+	return u.String()
+}
+
+// ExchangeCode exchanges an authorization code for a token, and stores it.
+func (c *Client) ExchangeCode(code string) (*Token, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	return c.requestToken(v)
+}
+
+// ExchangePin exchanges a PIN the user copied from Imgur for a token, and
+// stores it.
+func (c *Client) ExchangePin(pin string) (*Token, error) {
+	v := url.Values{}
+	v.Set("grant_type", "pin")
+	v.Set("pin", pin)
+	return c.requestToken(v)
+}
+
+// RefreshToken trades the stored refresh token for a new token, and stores
+// it in place of the old one.
+func (c *Client) RefreshToken() (*Token, error) {
+	c.tokenMu.Lock()
+	t, err := c.storedToken()
+	c.tokenMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.RefreshToken == "" {
+		return nil, fmt.Errorf("no refresh token available")
+	}
+	return c.requestToken(refreshValues(t))
+}
+
+// requestToken asks the token endpoint for a token and stores it, creating
+// a MemoryTokenStore if the client doesn't have a store yet.
+func (c *Client) requestToken(v url.Values) (*Token, error) {
+	t, err := c.fetchToken(v)
+	if err != nil {
+		return nil, err
+	}
+
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return t, c.storeToken(t)
+}
+
+// fetchToken asks the token endpoint for a token.  It doesn't touch the
+// store, so it's called without holding tokenMu.
+func (c *Client) fetchToken(v url.Values) (*Token, error) {
+	v.Set("client_id", c.clientID)
+	v.Set("client_secret", c.clientSecret)
+
+	u := c.AuthURL.ResolveReference(&url.URL{Path: "token"})
+	req, err := http.NewRequest("POST", u.String(), strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", formContentType)
+	req.Header.Add("User-Agent", c.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	t := &Token{}
+	if err := json.NewDecoder(resp.Body).Decode(t); err != nil {
+		return nil, err
+	}
+	if t.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access token")
+	}
+	if t.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// storeToken stores t, creating a MemoryTokenStore if the client doesn't
+// have a store yet.  A stored token ends any refresh backoff.  The caller
+// must hold tokenMu.
+func (c *Client) storeToken(t *Token) error {
+	if c.tokens == nil {
+		c.tokens = &MemoryTokenStore{}
+	}
+	if err := c.tokens.SetToken(t); err != nil {
+		return err
+	}
+	c.refreshFailures = 0
+	c.retryRefresh = time.Time{}
+	return nil
+}
+
+// storedToken returns the token in the client's store, if any.  The caller
+// must hold tokenMu.
+func (c *Client) storedToken() (*Token, error) {
+	if c.tokens == nil {
+		return nil, nil
+	}
+	return c.tokens.Token()
+}
+
+// refreshValues are the token endpoint parameters that trade t's refresh
+// token for a new token.
+func refreshValues(t *Token) url.Values {
+	v := url.Values{}
+	v.Set("grant_type", "refresh_token")
+	v.Set("refresh_token", t.RefreshToken)
+	return v
+}
+
+// Backoff between attempts to use a token that couldn't be read or
+// refreshed.  It doubles with every failure in a row.
+const (
+	minRefreshBackoff = 30 * time.Second
+	maxRefreshBackoff = 30 * time.Minute
+)
+
+// backOff puts off using the stored token after a failure to read or
+// refresh it.  The caller must hold tokenMu.
+func (c *Client) backOff(err error) {
+	d := minRefreshBackoff << uint(c.refreshFailures)
+	if d <= 0 || d > maxRefreshBackoff {
+		d = maxRefreshBackoff
+	}
+	c.refreshFailures++
+	c.retryRefresh = time.Now().Add(d)
+	log.Printf("imgur: OAuth2 token unusable, requests are anonymous for %v: %v", d, err)
+}
+
+// authorization returns the Authorization header value for a request:
+// Bearer auth when a token is stored, refreshing it first if it has
+// expired, and Client-ID otherwise.
+//
+// Most of the API works anonymously, so a request doesn't fail because the
+// token can't be read or refreshed, say because Imgur is down; it goes out
+// with the Client-ID, and the token is tried again after a backoff.  Only
+// one request refreshes the token at a time, without holding tokenMu, and
+// the requests made meanwhile use the Client-ID rather than wait for it.
+func (c *Client) authorization() string {
+	anonymous := fmt.Sprintf("Client-ID %s", c.clientID)
+
+	c.tokenMu.Lock()
+	if c.tokens == nil || c.refreshing || time.Now().Before(c.retryRefresh) {
+		c.tokenMu.Unlock()
+		return anonymous
+	}
+	t, err := c.tokens.Token()
+	switch {
+	case err != nil:
+		c.backOff(err)
+		c.tokenMu.Unlock()
+		return anonymous
+	case t == nil || (t.AccessToken == "" && t.RefreshToken == ""):
+		c.tokenMu.Unlock()
+		return anonymous
+	case !t.Expired():
+		c.tokenMu.Unlock()
+		return "Bearer " + t.AccessToken
+	case t.RefreshToken == "":
+		c.backOff(fmt.Errorf("access token expired and there is no refresh token"))
+		c.tokenMu.Unlock()
+		return anonymous
+	}
+	c.refreshing = true
+	c.tokenMu.Unlock()
+
+	t, err = c.fetchToken(refreshValues(t))
+
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.refreshing = false
+	if err != nil {
+		c.backOff(err)
+		return anonymous
+	}
+	if err := c.storeToken(t); err != nil {
+		// The old refresh token may be spent already, so use the new token
+		// while it lasts.
+		c.backOff(err)
+	}
+	return "Bearer " + t.AccessToken
+}
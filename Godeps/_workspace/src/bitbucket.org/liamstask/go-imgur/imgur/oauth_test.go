@@ -0,0 +1,202 @@
+package imgur
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	tokenResponse = `{"access_token":"access2","expires_in":3600,"token_type":"bearer","scope":null,"refresh_token":"refresh2","account_id":384077,"account_username":"dickbutt"}`
+)
+
+func TestAuthorizeURL(t *testing.T) {
+	c := NewClient(nil, "clientID", "clientSecret")
+
+	got, err := url.Parse(c.AuthorizeURL(ResponseTypePin, "xyz"))
+	if err != nil {
+		t.Fatalf("AuthorizeURL returned unparseable URL: %v", err)
+	}
+
+	if got.Host != "api.imgur.com" || got.Path != "/oauth2/authorize" {
+		t.Errorf("AuthorizeURL returned %v, want https://api.imgur.com/oauth2/authorize", got)
+	}
+
+	q := got.Query()
+	if q.Get("client_id") != "clientID" || q.Get("response_type") != "pin" || q.Get("state") != "xyz" {
+		t.Errorf("AuthorizeURL returned query %v", q)
+	}
+}
+
+func TestExchangePin(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if got := r.FormValue("grant_type"); got != "pin" {
+			t.Errorf("Request grant_type = %v, want %v", got, "pin")
+		}
+		if got := r.FormValue("pin"); got != "1234" {
+			t.Errorf("Request pin = %v, want %v", got, "1234")
+		}
+		if got := r.FormValue("client_secret"); got != "clientSecret" {
+			t.Errorf("Request client_secret = %v, want %v", got, "clientSecret")
+		}
+		fmt.Fprint(w, tokenResponse)
+	})
+	mux.HandleFunc("/image/abc", func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer access2")
+		fmt.Fprint(w, `{"data":{"id":"abc"},"success":true,"status":200}`)
+	})
+
+	tok, err := client.ExchangePin("1234")
+	if err != nil {
+		t.Fatalf("ExchangePin returned error: %v", err)
+	}
+	if tok.AccountUsername != "dickbutt" || tok.Expired() {
+		t.Errorf("ExchangePin returned %+v", tok)
+	}
+
+	if _, err := client.Image.Info("abc"); err != nil {
+		t.Errorf("Image.Info returned error: %v", err)
+	}
+}
+
+func TestExpiredTokenIsRefreshed(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	refreshes := 0
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		refreshes++
+		if got := r.FormValue("grant_type"); got != "refresh_token" {
+			t.Errorf("Request grant_type = %v, want %v", got, "refresh_token")
+		}
+		if got := r.FormValue("refresh_token"); got != "refresh1" {
+			t.Errorf("Request refresh_token = %v, want %v", got, "refresh1")
+		}
+		fmt.Fprint(w, tokenResponse)
+	})
+	mux.HandleFunc("/image/abc", func(w http.ResponseWriter, r *http.Request) {
+		testHeader(t, r, "Authorization", "Bearer access2")
+		fmt.Fprint(w, `{"data":{"id":"abc"},"success":true,"status":200}`)
+	})
+
+	store := &FileTokenStore{Path: filepath.Join(t.TempDir(), "token.json")}
+	store.SetToken(&Token{AccessToken: "access1", RefreshToken: "refresh1", Expiry: time.Now().Add(-time.Minute)})
+	client.SetTokenStore(store)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Image.Info("abc"); err != nil {
+			t.Errorf("Image.Info returned error: %v", err)
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("token refreshed %v times, want %v", refreshes, 1)
+	}
+
+	// Imgur rotates refresh tokens, so the new one must be persisted.
+	tok, err := store.Token()
+	if err != nil {
+		t.Fatalf("FileTokenStore.Token returned error: %v", err)
+	}
+	if tok.RefreshToken != "refresh2" {
+		t.Errorf("stored refresh token = %v, want %v", tok.RefreshToken, "refresh2")
+	}
+}
+
+func TestNoTokenUsesClientID(t *testing.T) {
+	c := NewClient(nil, "clientID", "clientSecret")
+	c.SetTokenStore(&MemoryTokenStore{})
+
+	req, err := c.NewRequest("GET", "image/abc", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+	testHeader(t, req, "Authorization", "Client-ID clientID")
+}
+
+func TestFailedRefreshFallsBackToClientID(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	refreshes := 0
+	down := true
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		refreshes++
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"data":{"error":"Over capacity"},"success":false,"status":503}`)
+			return
+		}
+		fmt.Fprint(w, tokenResponse)
+	})
+
+	authorization := func() string {
+		req, err := client.NewRequest("GET", "image/abc", nil)
+		if err != nil {
+			t.Fatalf("NewRequest returned error: %v", err)
+		}
+		return req.Header.Get("Authorization")
+	}
+
+	store := &MemoryTokenStore{token: &Token{RefreshToken: "refresh1"}}
+	client.SetTokenStore(store)
+	for i := 0; i < 2; i++ {
+		if got, want := authorization(), "Client-ID clientID"; got != want {
+			t.Errorf("Authorization = %v while Imgur is down, want %v", got, want)
+		}
+	}
+	// The refresh isn't tried again for every request, but the token is
+	// kept for when Imgur is back.
+	if refreshes != 1 {
+		t.Errorf("token refreshed %v times, want %v", refreshes, 1)
+	}
+	if tok, _ := store.Token(); tok == nil || tok.RefreshToken != "refresh1" {
+		t.Errorf("stored token = %+v, want refresh token refresh1", tok)
+	}
+
+	down = false
+	client.tokenMu.Lock()
+	client.retryRefresh = time.Now()
+	client.tokenMu.Unlock()
+	if got, want := authorization(), "Bearer access2"; got != want {
+		t.Errorf("Authorization = %v after the backoff, want %v", got, want)
+	}
+}
+
+func TestRefreshDoesNotBlockRequests(t *testing.T) {
+	imgurTestSetup()
+	defer imgurTestTeardown()
+
+	started, release := make(chan bool), make(chan bool)
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+		fmt.Fprint(w, tokenResponse)
+	})
+
+	client.SetTokenStore(&MemoryTokenStore{token: &Token{RefreshToken: "refresh1"}})
+	refreshed := make(chan string)
+	go func() {
+		req, _ := client.NewRequest("GET", "image/abc", nil)
+		refreshed <- req.Header.Get("Authorization")
+	}()
+	<-started
+
+	// Requests made while the token is being refreshed don't wait for it.
+	req, err := client.NewRequest("GET", "image/abc", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+	testHeader(t, req, "Authorization", "Client-ID clientID")
+
+	close(release)
+	if got, want := <-refreshed, "Bearer access2"; got != want {
+		t.Errorf("Authorization of the refreshing request = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+
+	"image"
+	"image/color"
+	"image/gif"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// The paths an animated overlay can move along, picked with ?path=.
+const (
+	pathBounce = "bounce" // hops up and down where it is
+	pathSlide  = "slide"  // slides in from the left, and stays
+	pathSpin   = "spin"   // turns round where it is
+	pathPeek   = "peek"   // peeks up from the bottom edge, and hides again
+)
+
+var overlayPaths = map[string]bool{pathBounce: true, pathSlide: true, pathSpin: true, pathPeek: true}
+
+// An animation is at most gifMaxSize pixels across either way and has
+// gifFrames frames, gifDelay hundredths of a second apart, so every one
+// costs about the same to render whatever it's of.
+const (
+	gifMaxSize = 400
+	gifFrames  = 20
+	gifDelay   = 6
+)
+
+// gifSample is the spacing of the pixels the palette is picked from.
+const gifSample = 3
+
+// writeAnimation writes out p as an animated GIF, its overlay moving along
+// the ?path= asked for, over its background scaled down to fit gifMaxSize
+// or ?w= pixels.
+func writeAnimation(res http.ResponseWriter, req *http.Request, p Page) {
+	path := req.URL.Query().Get("path")
+	if !overlayPaths[path] {
+		path = pathBounce
+	}
+	size := gifMaxSize
+	if w, err := strconv.Atoi(req.URL.Query().Get("w")); err == nil && w > 0 && w < size {
+		size = w
+	}
+
+	anim, err := Animate(p, path, size)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	res.Header().Set("Content-Type", "image/gif")
+	if err := gif.EncodeAll(res, anim); err != nil {
+		log.Printf("animate: %v", err)
+	}
+}
+
+// Animate renders p as gifFrames frames of its overlay moving along path,
+// over its background scaled down to fit size pixels.  Every frame shares
+// one palette, picked from the background and the overlay.
+func Animate(p Page, path string, size int) (*gif.GIF, error) {
+	over, err := pageOverlay(p)
+	if err != nil {
+		return nil, err
+	}
+	bg, err := compositeBackground(p)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := pageFilters(p)
+	base := fitImage(bg, size)
+	b := base.Bounds()
+	applyFilters(base, fc.background)
+
+	// The overlay is scaled once, rather than for every frame.
+	rect := overlayRect(b, over.Bounds(), p)
+	sprite := scaleOverlay(over, rect)
+	applyFilters(sprite, fc.overlay)
+
+	// So is anything written over it.
+	var caption *image.RGBA
+	if p.Caption != "" || p.TopText != "" || p.BottomText != "" {
+		caption = image.NewRGBA(b)
+		drawMeme(caption, p.TopText, p.BottomText)
+		if p.Caption != "" {
+			drawCaption(caption, p.Caption, p.CaptionAuthor)
+		}
+	}
+
+	// Filters of the whole composite are applied to every frame, so the
+	// palette is picked from the first.
+	var q *quantizer
+	anim := &gif.GIF{Config: image.Config{Width: b.Dx(), Height: b.Dy()}}
+	frame := image.NewRGBA(b)
+	for i := 0; i < gifFrames; i++ {
+		copy(frame.Pix, base.Pix)
+		drawSprite(frame, sprite, rect, path, float64(i)/gifFrames)
+		if caption != nil {
+			draw.Draw(frame, b, caption, b.Min, draw.Over)
+		}
+		applyFilters(frame, fc.all)
+		if q == nil {
+			q = newQuantizer(frame, sprite)
+		}
+		anim.Image = append(anim.Image, q.paletted(frame))
+		anim.Delay = append(anim.Delay, gifDelay)
+	}
+	anim.Config.ColorModel = q.palette
+	return anim, nil
+}
+
+// fitImage scales img down to fit within size pixels either way.
+func fitImage(img *image.RGBA, size int) *image.RGBA {
+	b := img.Bounds()
+	if b.Dx() <= size && b.Dy() <= size {
+		return img
+	}
+	w, h := size, max(1, b.Dy()*size/b.Dx())
+	if b.Dy() > b.Dx() {
+		w, h = max(1, b.Dx()*size/b.Dy()), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// drawSprite draws the overlay onto dst where path has it at t, from 0 to
+// 1 through the animation; rect is where it is on the still composite.
+func drawSprite(dst, sprite *image.RGBA, rect image.Rectangle, path string, t float64) {
+	b := dst.Bounds()
+	w, h := float64(rect.Dx()), float64(rect.Dy())
+	offset := image.Point{}
+
+	switch path {
+	case pathBounce:
+		offset.Y = -int(float64(b.Dy()) / 4 * math.Abs(math.Sin(2*math.Pi*t)))
+	case pathSlide:
+		// Eased out over the first half, then still.
+		s := math.Min(1, 2*t)
+		s = 1 - (1-s)*(1-s)
+		offset.X = int(-(float64(rect.Min.X) + w) * (1 - s))
+	case pathPeek:
+		// From below the bottom edge, up to show most of it for a while,
+		// then back down.
+		s := math.Min(1, 1.5*math.Sin(math.Pi*t))
+		offset.Y = b.Max.Y - int(0.7*h*s) - rect.Min.Y
+	case pathSpin:
+		theta := 2 * math.Pi * t
+		sin, cos := math.Sincos(theta)
+		cx, cy := float64(rect.Min.X)+w/2, float64(rect.Min.Y)+h/2
+		m := f64.Aff3{
+			cos, -sin, cx - cos*w/2 + sin*h/2,
+			sin, cos, cy - sin*w/2 - cos*h/2,
+		}
+		draw.ApproxBiLinear.Transform(dst, m, sprite, sprite.Bounds(), draw.Over, nil)
+		return
+	}
+
+	r := rect.Add(offset)
+	draw.Draw(dst, r, sprite, image.Point{}, draw.Over)
+}
+
+// quantizer maps colors onto a palette of at most 256 picked by median
+// cut, looking up each color at 5 bits a channel, which is plenty for a
+// GIF, and remembering what it found.
+type quantizer struct {
+	palette color.Palette
+	lookup  []int16 // by 15 bit color; -1 if not looked up yet
+}
+
+func newQuantizer(images ...*image.RGBA) *quantizer {
+	var samples []color.RGBA
+	for _, img := range images {
+		for i := 0; i+3 < len(img.Pix); i += 4 * gifSample {
+			if img.Pix[i+3] == 0xff {
+				samples = append(samples, color.RGBA{img.Pix[i], img.Pix[i+1], img.Pix[i+2], 0xff})
+			}
+		}
+	}
+
+	q := &quantizer{palette: medianCut(samples, 256), lookup: make([]int16, 1<<15)}
+	for i := range q.lookup {
+		q.lookup[i] = -1
+	}
+	return q
+}
+
+// paletted returns img in q's palette.
+func (q *quantizer) paletted(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+	dst := image.NewPaletted(b, q.palette)
+	for i, j := 0, 0; i < len(img.Pix); i, j = i+4, j+1 {
+		r, g, bl := img.Pix[i], img.Pix[i+1], img.Pix[i+2]
+		key := int(r>>3)<<10 | int(g>>3)<<5 | int(bl>>3)
+		if q.lookup[key] < 0 {
+			q.lookup[key] = int16(q.palette.Index(color.RGBA{r | r>>5, g | g>>5, bl | bl>>5, 0xff}))
+		}
+		dst.Pix[j] = uint8(q.lookup[key])
+	}
+	return dst
+}
+
+// medianCut picks up to n colors representing samples: the box of colors
+// they fill is split in two at the median of its longest side, and then
+// the box with the longest side of all, until there are n boxes, each of
+// which gives its average color.
+func medianCut(samples []color.RGBA, n int) color.Palette {
+	if len(samples) == 0 {
+		return color.Palette{color.Black}
+	}
+
+	type box struct {
+		colors  []color.RGBA
+		channel int // of the longest side
+		length  int
+	}
+	newBox := func(colors []color.RGBA) box {
+		ch, length := longestChannel(colors)
+		return box{colors, ch, length}
+	}
+
+	boxes := []box{newBox(samples)}
+	for len(boxes) < n {
+		best := 0
+		for i := range boxes {
+			if boxes[i].length > boxes[best].length {
+				best = i
+			}
+		}
+		if boxes[best].length == 0 {
+			break
+		}
+
+		colors, ch := boxes[best].colors, boxes[best].channel
+		sort.Slice(colors, func(i, j int) bool {
+			return channel(colors[i], ch) < channel(colors[j], ch)
+		})
+		mid := len(colors) / 2
+		boxes[best] = newBox(colors[:mid])
+		boxes = append(boxes, newBox(colors[mid:]))
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		var r, g, b int
+		for _, c := range box.colors {
+			r, g, b = r+int(c.R), g+int(c.G), b+int(c.B)
+		}
+		n := len(box.colors)
+		palette[i] = color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 0xff}
+	}
+	return palette
+}
+
+// longestChannel returns which channel of the colors in box spans the
+// widest range, and that range.
+func longestChannel(box []color.RGBA) (int, int) {
+	lo, hi := [3]int{255, 255, 255}, [3]int{}
+	for _, c := range box {
+		for ch := 0; ch < 3; ch++ {
+			v := channel(c, ch)
+			lo[ch], hi[ch] = min(lo[ch], v), max(hi[ch], v)
+		}
+	}
+	best := 0
+	for ch := 1; ch < 3; ch++ {
+		if hi[ch]-lo[ch] > hi[best]-lo[best] {
+			best = ch
+		}
+	}
+	return best, hi[best] - lo[best]
+}
+
+func channel(c color.RGBA, ch int) int {
+	switch ch {
+	case 0:
+		return int(c.R)
+	case 1:
+		return int(c.G)
+	}
+	return int(c.B)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// backgroundServer serves a w by h background composites are allowed to
+// fetch until t is done.
+func backgroundServer(t *testing.T, w, h int) *httptest.Server {
+	return imgurTestServer(t, http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0x80, 0xff})
+			}
+		}
+		png.Encode(res, img)
+	}))
+}
+
+// imgurTestServer starts a server of handler which composites are allowed
+// to fetch from, and stops it and takes the permission back when t is
+// done.
+func imgurTestServer(t *testing.T, handler http.Handler) *httptest.Server {
+	srv := httptest.NewServer(handler)
+	u, _ := url.Parse(srv.URL)
+	imgurHosts[u.Host] = true
+	t.Cleanup(func() {
+		delete(imgurHosts, u.Host)
+		srv.Close()
+	})
+	return srv
+}
+
+func TestAnimate(t *testing.T) {
+	srv := backgroundServer(t, 800, 600)
+
+	p := Page{ImgurSource: srv.URL + "/zHQ2rzI.png", Top: 40, Bottom: 30, Overlay: defaultOverlay}
+	for path := range overlayPaths {
+		anim, err := Animate(p, path, 200)
+		if err != nil {
+			t.Fatalf("Animate(%v) returned error: %v", path, err)
+		}
+		if len(anim.Image) != gifFrames || len(anim.Delay) != gifFrames {
+			t.Errorf("Animate(%v) returned %v frames, want %v", path, len(anim.Image), gifFrames)
+		}
+		if anim.Config.Width != 200 || anim.Config.Height != 150 {
+			t.Errorf("Animate(%v) returned %vx%v, want 200x150", path, anim.Config.Width, anim.Config.Height)
+		}
+		if n := len(anim.Config.ColorModel.(color.Palette)); n > 256 {
+			t.Errorf("Animate(%v) returned %v colors, want at most 256", path, n)
+		}
+	}
+}
+
+func TestMedianCut(t *testing.T) {
+	var samples []color.RGBA
+	for i := 0; i < 1000; i++ {
+		samples = append(samples, color.RGBA{uint8(i), uint8(i / 4), 0, 0xff})
+	}
+
+	for _, n := range []int{1, 2, 16, 256} {
+		if got := len(medianCut(samples, n)); got != n {
+			t.Errorf("medianCut returned %v colors, want %v", got, n)
+		}
+	}
+
+	// Fewer distinct colors than asked for gives just those.
+	red, blue := color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}
+	palette := medianCut([]color.RGBA{red, blue, red, blue}, 256)
+	if len(palette) != 2 || palette[palette.Index(red)] != red || palette[palette.Index(blue)] != blue {
+		t.Errorf("medianCut returned %v, want red and blue", palette)
+	}
+}
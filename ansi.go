@@ -0,0 +1,98 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Bounds and default for ?cols=, the width of terminal art in characters.
+const (
+	defaultArtCols = 80
+	minArtCols     = 10
+	maxArtCols     = 300
+)
+
+// asciiRamp runs from the darkest to the lightest character used by ?mono
+// art.
+const asciiRamp = " .:-=+*#%@"
+
+// terminalAgents are User-Agent prefixes of command line clients, which get
+// art rather than HTML when they'll take anything.
+var terminalAgents = []string{"curl/", "Wget/", "HTTPie/", "xh/", "fetch libfetch", "PowerShell/"}
+
+// isTerminal reports whether req comes from a command line client.
+func isTerminal(req *http.Request) bool {
+	ua := req.UserAgent()
+	for _, agent := range terminalAgents {
+		if strings.HasPrefix(ua, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// artCols returns the width of art asked for by ?cols=.
+func artCols(req *http.Request) int {
+	cols, err := strconv.Atoi(req.URL.Query().Get("cols"))
+	if err != nil {
+		return defaultArtCols
+	}
+	if cols < minArtCols {
+		return minArtCols
+	}
+	if cols > maxArtCols {
+		return maxArtCols
+	}
+	return cols
+}
+
+// writeArt draws img cols characters wide.  Each character covers two
+// pixels stacked, which is about square in a terminal: in color, as an
+// upper half block with the top pixel in the foreground and the bottom one
+// in the background; in mono, as the character from asciiRamp as light as
+// the pair.
+func writeArt(w io.Writer, img image.Image, cols int, mono bool) error {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return nil
+	}
+	rows := cols * b.Dy() / b.Dx() / 2
+	if rows < 1 {
+		rows = 1
+	}
+
+	small := image.NewRGBA(image.Rect(0, 0, cols, rows*2))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, b, draw.Src, nil)
+
+	out := bufio.NewWriter(w)
+	for y := 0; y < rows*2; y += 2 {
+		for x := 0; x < cols; x++ {
+			top := small.RGBAAt(x, y)
+			bottom := small.RGBAAt(x, y+1)
+			if mono {
+				lum := (luminance(top.R, top.G, top.B) + luminance(bottom.R, bottom.G, bottom.B)) / 2
+				out.WriteByte(asciiRamp[lum*(len(asciiRamp)-1)/255])
+				continue
+			}
+			fmt.Fprintf(out, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀",
+				top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+		}
+		if !mono {
+			out.WriteString("\x1b[0m")
+		}
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+// luminance returns the perceived brightness of a color, 0 to 255.
+func luminance(r, g, b uint8) int {
+	return (299*int(r) + 587*int(g) + 114*int(b)) / 1000
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestArtCols(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"", defaultArtCols},
+		{"?cols=abc", defaultArtCols},
+		{"?cols=40", 40},
+		{"?cols=0", minArtCols},
+		{"?cols=-5", minArtCols},
+		{"?cols=10", minArtCols},
+		{"?cols=300", maxArtCols},
+		{"?cols=100000", maxArtCols},
+	} {
+		if got := artCols(httptest.NewRequest("GET", "/cats"+tt.query, nil)); got != tt.want {
+			t.Errorf("artCols for %q returned %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	for _, tt := range []struct {
+		agent string
+		want  bool
+	}{
+		{"curl/8.4.0", true},
+		{"Wget/1.21", true},
+		{"HTTPie/3.2.2", true},
+		{"Mozilla/5.0 (X11; Linux x86_64)", false},
+		{"", false},
+	} {
+		req := httptest.NewRequest("GET", "/cats", nil)
+		req.Header.Set("User-Agent", tt.agent)
+		if got := isTerminal(req); got != tt.want {
+			t.Errorf("isTerminal for %q returned %v, want %v", tt.agent, got, tt.want)
+		}
+	}
+}
+
+func TestWriteArt(t *testing.T) {
+	// White on top, black below.
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	for _, tt := range []struct {
+		cols        int
+		mono        bool
+		first, last string
+	}{
+		{20, true, strings.Repeat("@", 20), strings.Repeat(" ", 20)},
+		{10, true, strings.Repeat("@", 10), strings.Repeat(" ", 10)},
+		// One line of upper half blocks, white over black.
+		{4, false, strings.Repeat("\x1b[38;2;255;255;255m\x1b[48;2;0;0;0m▀", 4) + "\x1b[0m",
+			strings.Repeat("\x1b[38;2;255;255;255m\x1b[48;2;0;0;0m▀", 4) + "\x1b[0m"},
+	} {
+		var buf bytes.Buffer
+		if err := writeArt(&buf, img, tt.cols, tt.mono); err != nil {
+			t.Fatalf("writeArt returned error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		// Each character covers two pixels stacked, so a 2:1 image is a
+		// quarter as many lines as it's characters wide.
+		if want := tt.cols / 4; len(lines) != want {
+			t.Fatalf("writeArt %v wide, mono %v, wrote %v lines, want %v", tt.cols, tt.mono, len(lines), want)
+		}
+		if lines[0] != tt.first || lines[len(lines)-1] != tt.last {
+			t.Errorf("writeArt %v wide, mono %v, wrote\n%q\n...\n%q\nwant\n%q\n...\n%q", tt.cols, tt.mono, lines[0], lines[len(lines)-1], tt.first, tt.last)
+		}
+	}
+}
+
+func TestWriteTextPermalink(t *testing.T) {
+	srv := backgroundServer(t, 80, 60)
+	p := Page{Place: "funny cats", ImgurSource: srv.URL + "/a.png", Top: 40, Bottom: 30, Overlay: defaultOverlay}
+
+	req := httptest.NewRequest("GET", "http://dickbutt.in/funny%20cats?mono&cols=20", nil)
+	res := httptest.NewRecorder()
+	writeText(res, req, p)
+
+	// It ends with the permalink, so the same picture can be fetched again.
+	lines := strings.Split(strings.TrimSuffix(res.Body.String(), "\n"), "\n")
+	last := lines[len(lines)-1]
+	const prefix = "http://dickbutt.in" + permalinkPath
+	if !strings.HasPrefix(last, prefix) {
+		t.Fatalf("writeText ended with %q, want a permalink", last)
+	}
+	got, err := pageFromToken(req, strings.TrimPrefix(last, prefix))
+	if err != nil || got.ImgurSource != p.ImgurSource || got.Top != p.Top || got.Bottom != p.Bottom {
+		t.Errorf("writeText links to %+v, %v, want the page written", got, err)
+	}
+	if lines[0] == "" || strings.Contains(lines[0], "\x1b") {
+		t.Errorf("writeText with ?mono wrote %q first, want plain ASCII art", lines[0])
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assetsPath is where assets are served from.
+const assetsPath = "/assets/"
+
+// Assets are served with precompressed variants for these encodings, most
+// preferred first.  A variant is either built into the binary next to the
+// asset, as name.br or name.gz, or for gzip made when the assets are loaded.
+var assetEncodings = []struct {
+	name string // Content-Encoding
+	ext  string // suffix of the precompressed file
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+var assets = mustLoadAssets()
+
+// Asset is a static file, held in memory with its precompressed variants.
+type Asset struct {
+	Name        string // path under assets/
+	Fingerprint string // Name with a hash of the content before the extension
+	ContentType string
+	ETag        string
+	Content     []byte
+	Encoded     map[string][]byte // by Content-Encoding
+}
+
+// Assets are the static files, by name and by fingerprinted name.
+type Assets struct {
+	byName        map[string]*Asset
+	byFingerprint map[string]*Asset
+}
+
+func mustLoadAssets() *Assets {
+	fsys, err := fs.Sub(embeddedAssets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	a, err := LoadAssets(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// LoadAssets reads every file in fsys, fingerprinting each and gzipping
+// those that are worth it and don't come with a .gz already.
+func LoadAssets(fsys fs.FS) (*Assets, error) {
+	a := &Assets{
+		byName:        make(map[string]*Asset),
+		byFingerprint: make(map[string]*Asset),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || isEncodedVariant(name) {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])[:12]
+		ext := path.Ext(name)
+		asset := &Asset{
+			Name:        name,
+			Fingerprint: strings.TrimSuffix(name, ext) + "." + hash + ext,
+			ContentType: mime.TypeByExtension(ext),
+			ETag:        `"` + hash + `"`,
+			Content:     content,
+			Encoded:     make(map[string][]byte),
+		}
+		if asset.ContentType == "" {
+			asset.ContentType = http.DetectContentType(content)
+		}
+
+		for _, enc := range assetEncodings {
+			if variant, err := fs.ReadFile(fsys, name+enc.ext); err == nil {
+				asset.Encoded[enc.name] = variant
+			}
+		}
+		if _, ok := asset.Encoded["gzip"]; !ok {
+			if gz := gzipped(content); gz != nil {
+				asset.Encoded["gzip"] = gz
+			}
+		}
+
+		a.byName[asset.Name] = asset
+		a.byFingerprint[asset.Fingerprint] = asset
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// isEncodedVariant reports whether name is a precompressed copy of another
+// asset.
+func isEncodedVariant(name string) bool {
+	for _, enc := range assetEncodings {
+		if strings.HasSuffix(name, enc.ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipped returns content gzipped, or nil if that doesn't save at least a
+// tenth of it, as with images that are compressed already.
+func gzipped(content []byte) []byte {
+	var buf bytes.Buffer
+	gz, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	gz.Write(content)
+	gz.Close()
+	if buf.Len() > len(content)*9/10 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// Open returns the content of the asset name.
+func (a *Assets) Open(name string) (*bytes.Reader, error) {
+	asset, ok := a.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("no asset %q", name)
+	}
+	return bytes.NewReader(asset.Content), nil
+}
+
+// URL returns the fingerprinted path of the asset name.  It's an error for
+// there to be no such asset, so a typo in a template is caught on the first
+// render rather than by a broken image.
+func (a *Assets) URL(name string) (string, error) {
+	asset, ok := a.byName[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return "", fmt.Errorf("no asset %q", name)
+	}
+	return assetsPath + asset.Fingerprint, nil
+}
+
+// AssetHandler serves an asset.  Fingerprinted names never change content,
+// so they're cached forever; plain names are still served, for links from
+// elsewhere, but have to be revalidated.
+func AssetHandler(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	if asset, ok := assets.byFingerprint[name]; ok {
+		serveAsset(res, req, asset, "public, max-age=31536000, immutable")
+	} else if asset, ok := assets.byName[name]; ok {
+		serveAsset(res, req, asset, "public, no-cache")
+	} else {
+		http.NotFound(res, req)
+	}
+}
+
+// serveAsset writes out asset, precompressed if the client accepts it.
+func serveAsset(res http.ResponseWriter, req *http.Request, asset *Asset, cacheControl string) {
+	h := res.Header()
+	h.Set("Cache-Control", cacheControl)
+
+	content, etag := asset.Content, asset.ETag
+	if len(asset.Encoded) > 0 {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	for _, enc := range assetEncodings {
+		if variant, ok := asset.Encoded[enc.name]; ok && acceptsEncoding(req, enc.name) {
+			h.Set("Content-Encoding", enc.name)
+			content = variant
+			// Each encoding is a different representation, so needs a
+			// different ETag.
+			etag = strings.TrimSuffix(etag, `"`) + "-" + enc.name + `"`
+			break
+		}
+	}
+
+	h.Set("Content-Type", asset.ContentType)
+	h.Set("ETag", etag)
+	http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(content))
+}
+
+// acceptsEncoding reports whether req's Accept-Encoding allows encoding.
+func acceptsEncoding(req *http.Request, encoding string) bool {
+	q := 0.0
+	for _, part := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding != encoding && coding != "*" {
+			continue
+		}
+
+		partQ := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if f, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					partQ = f
+				}
+			}
+		}
+		// The encoding itself overrides "*".
+		if coding == encoding {
+			return partQ > 0
+		}
+		q = partQ
+	}
+	return q > 0
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// testAssets has a stylesheet worth gzipping, a script that comes with its
+// own .br and .gz, and an image that doesn't shrink.
+func testAssets(t *testing.T) *Assets {
+	a, err := LoadAssets(fstest.MapFS{
+		"site.css":     {Data: []byte(strings.Repeat("body { color: black; }\n", 50))},
+		"app.js":       {Data: []byte(strings.Repeat("console.log(1);\n", 50))},
+		"app.js.br":    {Data: []byte("brotli")},
+		"app.js.gz":    {Data: []byte("gzip")},
+		"img/logo.png": {Data: []byte("\x89PNG\r\n\x1a\n")},
+	})
+	if err != nil {
+		t.Fatalf("LoadAssets returned error: %v", err)
+	}
+	return a
+}
+
+// getAsset serves path under assetsPath through AssetHandler, as the router
+// would.
+func getAsset(path, acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+	r := mux.NewRouter()
+	r.HandleFunc(assetsPath+"{name:.+}", AssetHandler)
+
+	req := httptest.NewRequest("GET", "http://dickbutt.in"+assetsPath+path, nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	return res
+}
+
+func TestLoadAssets(t *testing.T) {
+	a := testAssets(t)
+
+	for _, tt := range []struct {
+		name, base, ext, contentType string
+		encodings                    []string
+	}{
+		{"site.css", "site", ".css", "text/css; charset=utf-8", []string{"gzip"}},
+		{"app.js", "app", ".js", "text/javascript; charset=utf-8", []string{"br", "gzip"}},
+		{"img/logo.png", "img/logo", ".png", "image/png", nil},
+	} {
+		asset, ok := a.byName[tt.name]
+		if !ok {
+			t.Errorf("LoadAssets didn't load %v", tt.name)
+			continue
+		}
+		if a.byFingerprint[asset.Fingerprint] != asset {
+			t.Errorf("%v isn't found by its fingerprint %v", tt.name, asset.Fingerprint)
+		}
+		sum := sha256.Sum256(asset.Content)
+		hash := hex.EncodeToString(sum[:])[:12]
+		if want := tt.base + "." + hash + tt.ext; asset.Fingerprint != want {
+			t.Errorf("%v is fingerprinted %v, want %v", tt.name, asset.Fingerprint, want)
+		}
+		if want := `"` + hash + `"`; asset.ETag != want {
+			t.Errorf("%v has ETag %v, want %v", tt.name, asset.ETag, want)
+		}
+		if asset.ContentType != tt.contentType {
+			t.Errorf("%v has Content-Type %v, want %v", tt.name, asset.ContentType, tt.contentType)
+		}
+		if len(asset.Encoded) != len(tt.encodings) {
+			t.Errorf("%v has %v encodings, want %v", tt.name, len(asset.Encoded), tt.encodings)
+		}
+		for _, enc := range tt.encodings {
+			if _, ok := asset.Encoded[enc]; !ok {
+				t.Errorf("%v has no %v variant", tt.name, enc)
+			}
+		}
+	}
+
+	// Precompressed variants aren't assets of their own.
+	for _, name := range []string{"app.js.br", "app.js.gz"} {
+		if _, ok := a.byName[name]; ok {
+			t.Errorf("LoadAssets loaded %v as an asset", name)
+		}
+	}
+
+	// The script's own .gz is used rather than one made on loading.
+	if got := string(a.byName["app.js"].Encoded["gzip"]); got != "gzip" {
+		t.Errorf("app.js is gzipped as %q, want its own .gz", got)
+	}
+}
+
+func TestAssetURL(t *testing.T) {
+	a := testAssets(t)
+
+	for _, name := range []string{"site.css", "/site.css"} {
+		url, err := a.URL(name)
+		if err != nil || url != assetsPath+a.byName["site.css"].Fingerprint {
+			t.Errorf("URL(%v) returned %v, %v, want the fingerprinted path", name, url, err)
+		}
+	}
+	if _, err := a.URL("missing.css"); err == nil {
+		t.Errorf("URL(missing.css) returned no error")
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	for _, tt := range []struct {
+		accept, encoding string
+		want             bool
+	}{
+		{"", "gzip", false},
+		{"gzip", "gzip", true},
+		{"gzip, deflate, br", "br", true},
+		{"GZIP", "gzip", true},
+		{"deflate", "gzip", false},
+		{"gzip;q=0", "gzip", false},
+		{"gzip; q=0.5", "gzip", true},
+		{"*", "br", true},
+		{"*;q=0", "br", false},
+		{"br;q=0, *", "br", false},
+		{"gzip, *;q=0", "gzip", true},
+		{"br;q=abc", "br", true},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		if got := acceptsEncoding(req, tt.encoding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %v) returned %v, want %v", tt.accept, tt.encoding, got, tt.want)
+		}
+	}
+}
+
+func TestAssetHandler(t *testing.T) {
+	defer func(old *Assets) { assets = old }(assets)
+	assets = testAssets(t)
+	css, js, png := assets.byName["site.css"], assets.byName["app.js"], assets.byName["img/logo.png"]
+	withEncoding := func(etag, enc string) string {
+		return strings.TrimSuffix(etag, `"`) + "-" + enc + `"`
+	}
+
+	for _, tt := range []struct {
+		name, path, accept string
+		wantEncoding       string
+		wantETag           string
+		wantBody           []byte
+		immutable, vary    bool
+	}{
+		{"fingerprinted", css.Fingerprint, "", "", css.ETag, css.Content, true, true},
+		{"plain name", css.Name, "", "", css.ETag, css.Content, false, true},
+		{"gzip", css.Fingerprint, "gzip, deflate", "gzip", withEncoding(css.ETag, "gzip"), css.Encoded["gzip"], true, true},
+		{"br preferred", js.Fingerprint, "gzip, br", "br", withEncoding(js.ETag, "br"), []byte("brotli"), true, true},
+		{"br refused", js.Fingerprint, "gzip, br;q=0", "gzip", withEncoding(js.ETag, "gzip"), []byte("gzip"), true, true},
+		{"nothing to gzip", png.Fingerprint, "gzip", "", png.ETag, png.Content, true, false},
+	} {
+		res := getAsset(tt.path, tt.accept, "")
+		if res.Code != http.StatusOK {
+			t.Errorf("%v: returned %v, want %v", tt.name, res.Code, http.StatusOK)
+			continue
+		}
+		h := res.Header()
+		if got := h.Get("Content-Encoding"); got != tt.wantEncoding {
+			t.Errorf("%v: Content-Encoding is %q, want %q", tt.name, got, tt.wantEncoding)
+		}
+		if got := h.Get("ETag"); got != tt.wantETag {
+			t.Errorf("%v: ETag is %v, want %v", tt.name, got, tt.wantETag)
+		}
+		if !bytes.Equal(res.Body.Bytes(), tt.wantBody) {
+			t.Errorf("%v: body is %q, want %q", tt.name, res.Body.Bytes(), tt.wantBody)
+		}
+		if got := strings.Contains(h.Get("Cache-Control"), "immutable"); got != tt.immutable {
+			t.Errorf("%v: Cache-Control is %q, want immutable %v", tt.name, h.Get("Cache-Control"), tt.immutable)
+		}
+		if got := h.Get("Vary") == "Accept-Encoding"; got != tt.vary {
+			t.Errorf("%v: Vary is %q, want Accept-Encoding %v", tt.name, h.Get("Vary"), tt.vary)
+		}
+	}
+
+	// What's made on loading really is gzip.
+	gz, err := gzip.NewReader(bytes.NewReader(css.Encoded["gzip"]))
+	if err != nil {
+		t.Fatalf("gzip.NewReader returned error: %v", err)
+	}
+	if body, _ := io.ReadAll(gz); !bytes.Equal(body, css.Content) {
+		t.Errorf("site.css gzips to %q, want its content", body)
+	}
+
+	if res := getAsset("missing.css", "", ""); res.Code != http.StatusNotFound {
+		t.Errorf("A missing asset returned %v, want %v", res.Code, http.StatusNotFound)
+	}
+}
+
+func TestAssetNotModified(t *testing.T) {
+	defer func(old *Assets) { assets = old }(assets)
+	assets = testAssets(t)
+	css := assets.byName["site.css"]
+	gzipETag := strings.TrimSuffix(css.ETag, `"`) + "-gzip" + `"`
+
+	for _, tt := range []struct {
+		accept, ifNoneMatch string
+		want                int
+	}{
+		{"", css.ETag, http.StatusNotModified},
+		{"gzip", gzipETag, http.StatusNotModified},
+		{"gzip", "*", http.StatusNotModified},
+		// An ETag for one encoding doesn't match another.
+		{"gzip", css.ETag, http.StatusOK},
+		{"", gzipETag, http.StatusOK},
+		{"", `"stale"`, http.StatusOK},
+	} {
+		res := getAsset(css.Fingerprint, tt.accept, tt.ifNoneMatch)
+		if res.Code != tt.want {
+			t.Errorf("If-None-Match %v with Accept-Encoding %q returned %v, want %v", tt.ifNoneMatch, tt.accept, res.Code, tt.want)
+		}
+		if tt.want == http.StatusNotModified && res.Body.Len() != 0 {
+			t.Errorf("If-None-Match %v returned a body with a 304", tt.ifNoneMatch)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/norm"
+
+	"image"
+	"image/color"
+	"math"
+)
+
+const (
+	// captionLines is the most lines of caption drawn onto a composite;
+	// longer comments are cut short.
+	captionLines = 4
+
+	// captionMaxRunes is the most of a comment laid out, which is more
+	// than captionLines ever hold.
+	captionMaxRunes = 500
+
+	// captionSize is how tall captions are drawn relative to the
+	// composite's width, which fits roughly 40 characters across.
+	captionSize = 0.035
+)
+
+// drawCaption draws text, attributed to author, in a band across the
+// bottom of dst.  It's drawn in memeFont, so comments in other scripts
+// come out as well as meme text does.
+func drawCaption(dst *image.RGBA, text, author string) {
+	b := dst.Bounds()
+	size := math.Max(10, captionSize*float64(b.Dx()))
+	face := memeFace(size)
+	defer face.Close()
+
+	pad := int(size / 2)
+	width := fixed.I(b.Dx() - 2*pad)
+	lines, _ := wrapMeme(face, glyphText(norm.NFKC.String(text), captionMaxRunes), width, captionLines)
+	if author != "" {
+		byline, _ := wrapMeme(face, "- "+glyphText(norm.NFKC.String(author), captionMaxRunes), width, 1)
+		lines = append(lines, byline...)
+	}
+
+	lineH := int(size * memeLineHeight)
+	band := image.Rect(b.Min.X, b.Max.Y-len(lines)*lineH-2*pad, b.Max.X, b.Max.Y)
+	draw.Draw(dst, band, image.NewUniform(color.RGBA{0, 0, 0, 0x99}), image.Point{}, draw.Over)
+
+	d := &font.Drawer{Dst: dst, Src: image.White, Face: face}
+	ascent := face.Metrics().Ascent
+	for i, line := range lines {
+		x := b.Min.X + (b.Dx()-font.MeasureString(face, line).Round())/2
+		y := band.Min.Y + pad + i*lineH + (lineH-int(size))/2
+		d.Dot = fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y) + ascent}
+		d.DrawString(line)
+	}
+}
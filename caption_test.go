@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// captionBand returns how many rows up from the bottom of img the caption
+// band reaches, and whether any of it is drawn on.
+func captionBand(img *image.RGBA) (rows int, drawn bool) {
+	b := img.Bounds()
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		if img.RGBAAt(b.Min.X, y) == (color.RGBA{0, 0, 0, 0}) {
+			break
+		}
+		rows++
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c := img.RGBAAt(x, y); c.R > 0x80 {
+				drawn = true
+			}
+		}
+	}
+	return rows, drawn
+}
+
+func TestDrawCaption(t *testing.T) {
+	const width = 400
+	size := captionSize * width
+	lineH := int(size * memeLineHeight)
+
+	for _, tt := range []struct {
+		text, author string
+		lines        int
+	}{
+		{"A comment", "", 1},
+		{"A comment", "someone", 2},
+		// Comments in other scripts are drawn too, not just ASCII.
+		{"Ωμέγα café ☃", "Ölaf", 2},
+		{strings.Repeat("far too long a comment ", 40), "someone", captionLines + 1},
+	} {
+		img := image.NewRGBA(image.Rect(0, 0, width, 300))
+		drawCaption(img, tt.text, tt.author)
+
+		rows, drawn := captionBand(img)
+		if want := tt.lines*lineH + 2*int(size/2); rows != want {
+			t.Errorf("drawCaption(%q, %q) drew a band %v high, want %v lines, %v", tt.text, tt.author, rows, tt.lines, want)
+		}
+		if !drawn {
+			t.Errorf("drawCaption(%q, %q) drew no text", tt.text, tt.author)
+		}
+	}
+}
+
+func TestDrawCaptionNonASCII(t *testing.T) {
+	// Greek letters come out as themselves rather than as the same
+	// replacement glyph, so different words don't look alike.
+	draw := func(text string) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 400, 100))
+		drawCaption(img, text, "")
+		return img
+	}
+	if a, b := draw("Ωμέγα"), draw("Ψυχής"); string(a.Pix) == string(b.Pix) {
+		t.Errorf("drawCaption drew Greek words alike")
+	}
+	if got := glyphText("Ωμέγα café", captionMaxRunes); got != "Ωμέγα café" {
+		t.Errorf("glyphText kept %q, want the Greek and the accent", got)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// overlayScale is the overlay's width relative to the background, about
+// what it covers on the page in a laptop-sized browser window.
+const overlayScale = 0.2
+
+// maxImageBytes bounds how much of a background we're willing to download,
+// and maxImagePixels how big it can be once decoded, since a small PNG can
+// be huge.
+const (
+	maxImageBytes  = 20 << 20
+	maxImagePixels = 16 << 20
+)
+
+// imgurHosts are the only hosts backgrounds are fetched from, so the
+// renderer can't be pointed at arbitrary URLs.
+var imgurHosts = map[string]bool{
+	"imgur.com":   true,
+	"i.imgur.com": true,
+	"s.imgur.com": true,
+}
+
+// imageClient fetches backgrounds.  It gives up on an upstream that stalls,
+// and won't follow a redirect off imgurHosts.
+var imageClient = &http.Client{
+	Timeout: 30 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if !imgurHosts[req.URL.Host] {
+			return fmt.Errorf("refusing to follow redirect to %q", req.URL.Host)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after %d redirects", len(via))
+		}
+		return nil
+	},
+}
+
+// overlays caches the decoded overlay assets.
+var overlays = struct {
+	sync.Mutex
+	images map[string]image.Image
+}{images: make(map[string]image.Image)}
+
+// loadOverlay decodes the overlay asset name the first time it's needed.
+func loadOverlay(name string) (image.Image, error) {
+	overlays.Lock()
+	defer overlays.Unlock()
+
+	if img, ok := overlays.images[name]; ok {
+		return img, nil
+	}
+	r, err := assets.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	overlays.images[name] = img
+	return img, nil
+}
+
+// fetchImage downloads and decodes an image hosted on Imgur.
+func fetchImage(src string) (image.Image, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if !imgurHosts[u.Host] {
+		return nil, fmt.Errorf("refusing to fetch image from %q", u.Host)
+	}
+
+	res, err := imageClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", src, res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxImageBytes))
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("%s is too big: %dx%d", src, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Composite renders a page server-side: the overlay drawn onto the
+// background, or a mashup's panels, at the same relative position the page
+// puts it.
+func Composite(p Page) (*image.RGBA, error) {
+	over, err := pageOverlay(p)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := compositeBackground(p)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := pageFilters(p)
+	if !fc.empty() {
+		dst = fitImage(dst, filterMaxSize)
+	}
+	applyFilters(dst, fc.background)
+
+	rect := overlayRect(dst.Bounds(), over.Bounds(), p)
+	sprite := scaleOverlay(over, rect)
+	applyFilters(sprite, fc.overlay)
+	draw.Draw(dst, rect, sprite, image.Point{}, draw.Over)
+
+	drawMeme(dst, p.TopText, p.BottomText)
+	if p.Caption != "" {
+		drawCaption(dst, p.Caption, p.CaptionAuthor)
+	}
+	applyFilters(dst, fc.all)
+
+	return dst, nil
+}
+
+// scaleOverlay returns the overlay scaled to fit rect.
+func scaleOverlay(over image.Image, rect image.Rectangle) *image.RGBA {
+	sprite := image.NewRGBA(image.Rect(0, 0, max(1, rect.Dx()), max(1, rect.Dy())))
+	draw.CatmullRom.Scale(sprite, sprite.Bounds(), over, over.Bounds(), draw.Src, nil)
+	return sprite
+}
+
+// pageOverlay returns p's overlay image.
+func pageOverlay(p Page) (image.Image, error) {
+	name := p.Overlay
+	if name == "" {
+		name = defaultOverlay
+	}
+	return loadOverlay(name)
+}
+
+// compositeBackground renders what goes behind p's overlay: its
+// background, or its mashup's panels.
+func compositeBackground(p Page) (*image.RGBA, error) {
+	if len(p.Panels) > 0 {
+		return compositePanels(p.Panels)
+	}
+
+	bg, err := fetchImage(p.ImgurSource)
+	if err != nil {
+		return nil, err
+	}
+	b := bg.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), bg, b.Min, draw.Src)
+	return dst, nil
+}
+
+// overlayRect returns where on a background of bounds b an overlay of
+// bounds ob goes, for p.
+func overlayRect(b, ob image.Rectangle, p Page) image.Rectangle {
+	w := int(float64(b.Dx()) * overlayScale)
+	h := w * ob.Dy() / ob.Dx()
+	x := b.Dx() * p.Bottom / 100
+	y := b.Dy() * p.Top / 100
+	return image.Rect(x, y, x+w, y+h)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// hugePNG returns a tiny PNG whose header claims it's w by h, which is all
+// it takes to be decoded into that many pixels.
+func hugePNG(w, h uint32) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+
+	// The IHDR chunk follows the 8 byte signature, its length and type.
+	ihdr := data[16:29]
+	binary.BigEndian.PutUint32(ihdr[0:], w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestFetchImageTooBig(t *testing.T) {
+	srv := imgurTestServer(t, http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write(hugePNG(100000, 100000))
+	}))
+
+	if _, err := fetchImage(srv.URL + "/huge.png"); err == nil || !strings.Contains(err.Error(), "too big") {
+		t.Errorf("fetchImage of a 100000x100000 image returned %v, want it too big", err)
+	}
+}
+
+func TestFetchImageRedirects(t *testing.T) {
+	bg := backgroundServer(t, 8, 8)
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		png.Encode(res, image.NewGray(image.Rect(0, 0, 8, 8)))
+	}))
+	defer elsewhere.Close()
+
+	srv := imgurTestServer(t, http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/imgur.png" {
+			http.Redirect(res, req, bg.URL+"/a.png", http.StatusFound)
+			return
+		}
+		http.Redirect(res, req, elsewhere.URL+"/a.png", http.StatusFound)
+	}))
+
+	if _, err := fetchImage(srv.URL + "/imgur.png"); err != nil {
+		t.Errorf("fetchImage redirected within Imgur returned %v", err)
+	}
+	if _, err := fetchImage(srv.URL + "/elsewhere.png"); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("fetchImage redirected off Imgur returned %v, want it refused", err)
+	}
+}
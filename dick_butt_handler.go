@@ -3,51 +3,130 @@ package main
 import (
 	"github.com/gorilla/mux"
 
-	"fmt"
-	"html/template"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
-var dickTemplate = `
-{{define "page"}}
-<html>
-	<head>
-		<style>
-			img {
-				position: absolute;
-			}
-		</style>
-	</head>
-	<body style="background-image: url('{{.ImgurSource}}'); background-size: cover; background-position: center;">
-	<a href='{{.Place}}'><img style="top:{{.Top}}%; left:{{.Bottom}}%" src="/assets/dickbutt.png"/></a>
-	</body>
-</html>
-{{end}}
-`
-
 type Page struct {
-	ImgurSource string
-	Top         int
-	Bottom      int
-	Place       string
+	ImgurSource      string
+	Background       string // ImgurSource, through the image proxy and at the size picked for the client
+	BackgroundSrcset string
+	Width            int // Of the background, or of a mashup; 0 if unknown
+	Height           int
+	Animated         bool // Whether the background is animated
+	Top              int
+	Bottom           int
+	Place            string
+	ItemID           string
+	Title            string
+	Author           string
+	Permalink        string
+	Points           int
+	Caption          string
+	CaptionAuthor    string
+	TopText          string  // Meme text along the top of the image
+	BottomText       string  // and along the bottom
+	Filter           string  // The filters applied to the composite; see parseFilters
+	Overlay          string  // The asset drawn over the background
+	Layout           string  // How a mashup's panels are laid out
+	Panels           []Panel // A mashup's backgrounds, in place of the one above
+	PageURL          string  // The permalink of this exact page, set when it's written out
+	ImageURL         string  // The permalink of its composite
+	OEmbedURL        string
 }
 
+// NewPage places the overlay at a random spot over bg, sized for the client
+// making req.
+func NewPage(req *http.Request, place string, bg Background) Page {
+	src, srcset := sizeBackground(req, bg)
+	return Page{
+		ImgurSource:      bg.Link,
+		Background:       src,
+		BackgroundSrcset: srcset,
+		Width:            bg.Width,
+		Height:           bg.Height,
+		Animated:         bg.Animated,
+		Top:              rand.Intn(80),
+		Bottom:           rand.Intn(80),
+		Place:            place,
+		ItemID:           bg.ID,
+		Title:            bg.Title,
+		Author:           bg.Author,
+		Permalink:        bg.Permalink,
+		Points:           bg.Points,
+		Overlay:          requestOverlay(req),
+	}
+}
+
+// DickButtHandler serves a place in whichever format the client asks for,
+// by extension or Accept header; see placeFormat.  Several places separated
+// by "+" or "/" make a mashup.  Reserved names aren't places, and are never
+// searched for.  Any other spelling of a place than its canonical one is
+// redirected to it; see CanonicalMashup.
 func DickButtHandler(res http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	p := Page{
-		ImgurSource: ImgurSearcher(vars["place"]),
-		Top:         rand.Intn(80),
-		Bottom:      rand.Intn(80),
-		Place:       vars["place"],
+	name := mux.Vars(req)["place"]
+	for _, part := range strings.Split(name, "/") {
+		if isReservedPlace(part) {
+			http.NotFound(res, req)
+			return
+		}
+	}
+	requested, format := placeFormat(res, req, name)
+
+	places := CanonicalMashup(requested)
+	if len(places) == 0 {
+		http.NotFound(res, req)
+		return
+	}
+	place := mashupPlace(places)
+	if place != requested {
+		redirectToPlace(res, req, place, name[len(requested):])
+		return
 	}
-	fmt.Println(p)
-	templ, err := template.New("page").Parse(dickTemplate)
-	if err != nil {
-		panic(err)
+
+	servePlace(res, req, places, format)
+}
+
+// servePlace serves the canonical places, or the mashup of them, as format.
+func servePlace(res http.ResponseWriter, req *http.Request, places []string, format string) {
+	if format == "" {
+		http.Error(res, "Not Acceptable", http.StatusNotAcceptable)
+		return
 	}
-	err = templ.ExecuteTemplate(res, "page", p)
-	if err != nil {
-		panic(err)
+	placeStats.Record(mashupPlace(places))
+	render(res, req, newPlacePage(req, places), format)
+}
+
+// newPlacePage makes a page for the canonical places: a plain page for
+// one, and a mashup for more.
+func newPlacePage(req *http.Request, places []string) Page {
+	if len(places) == 1 {
+		return NewPage(req, places[0], ImgurSearcher(placeQuery(places[0])))
 	}
+	return NewMashupPage(req, places, mashupLayout(req, len(places)))
+}
+
+func placePath(place string) string {
+	return url.PathEscape(place)
+}
+
+// renderPage writes out p as HTML, after applying the page modes asked for
+// in req's query string.
+func renderPage(res http.ResponseWriter, req *http.Request, p Page) {
+	render(res, req, p, "text/html")
+}
+
+// writePage writes out p as HTML, in the theme for req.
+func writePage(res http.ResponseWriter, req *http.Request, p Page) {
+	res.Header().Set("Accept-CH", clientHints)
+	res.Header().Add("Vary", clientHints)
+
+	page, image := pagePermalink(p)
+	p.PageURL = absURL(req, page)
+	p.ImageURL = absURL(req, image)
+	p.OEmbedURL = absURL(req, "/oembed?format=json&url="+url.QueryEscape(p.PageURL))
+
+	executeTemplate(res, req, "page", p)
 }
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// From Discord's interactions API.
+const (
+	// Interaction types
+	discordPing      = 1
+	discordCommand   = 2
+	discordComponent = 3
+
+	// Response types
+	discordPong            = 1
+	discordMessage         = 4 // CHANNEL_MESSAGE_WITH_SOURCE
+	discordDeferredMessage = 5 // DEFERRED_CHANNEL_MESSAGE_WITH_SOURCE
+	discordDeferredUpdate  = 6 // DEFERRED_UPDATE_MESSAGE
+	discordUpdateMessage   = 7
+
+	// Component types and styles
+	discordActionRow       = 1
+	discordButton          = 2
+	discordSecondaryButton = 2
+
+	// Message flags
+	discordEphemeral = 1 << 6
+)
+
+// The reroll button's custom_id is discordRerollPrefix followed by its
+// place, and a custom_id holds at most discordMaxCustomID bytes.
+const (
+	discordRerollPrefix = "reroll:"
+	discordMaxCustomID  = 100
+)
+
+// discordAPI is where follow-ups to deferred responses are sent.
+const discordAPI = "https://discord.com/api/v10"
+
+// discordDeadline is how long an interaction waits for its page before
+// answering that it's still looking; Discord gives up after 3 seconds.
+var discordDeadline = 2500 * time.Millisecond
+
+var discordClient = &http.Client{Timeout: 10 * time.Second}
+
+// discordPublicKey verifies that requests come from Discord, from
+// DISCORD_PUBLIC_KEY in hex; the integration is off without it.
+var discordPublicKey = mustParseDiscordKey(os.Getenv("DISCORD_PUBLIC_KEY"))
+
+var errDiscordSignature = errors.New("bad Discord signature")
+
+func mustParseDiscordKey(s string) ed25519.PublicKey {
+	if s == "" {
+		return nil
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		panic("DISCORD_PUBLIC_KEY is not a hex encoded Ed25519 public key")
+	}
+	return ed25519.PublicKey(key)
+}
+
+// DiscordInteraction is the part of an interaction we use.
+type DiscordInteraction struct {
+	Type          int    `json:"type"`
+	ApplicationID string `json:"application_id"`
+	Token         string `json:"token"`
+	Data          struct {
+		Name     string `json:"name"`
+		CustomID string `json:"custom_id"`
+		Options  []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// option returns the string value of the command option name.
+func (i *DiscordInteraction) option(name string) string {
+	for _, opt := range i.Data.Options {
+		var s string
+		if opt.Name == name && json.Unmarshal(opt.Value, &s) == nil {
+			return s
+		}
+	}
+	return ""
+}
+
+type DiscordResponse struct {
+	Type int                  `json:"type"`
+	Data *DiscordResponseData `json:"data,omitempty"`
+}
+
+type DiscordResponseData struct {
+	Content    string             `json:"content,omitempty"`
+	Embeds     []DiscordEmbed     `json:"embeds,omitempty"`
+	Components []DiscordComponent `json:"components,omitempty"`
+	Flags      int                `json:"flags,omitempty"`
+}
+
+type DiscordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Image       *DiscordEmbedImage  `json:"image,omitempty"`
+	Footer      *DiscordEmbedFooter `json:"footer,omitempty"`
+	Fields      []DiscordEmbedField `json:"fields,omitempty"`
+}
+
+type DiscordEmbedImage struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+type DiscordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+type DiscordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type DiscordComponent struct {
+	Type       int                `json:"type"`
+	Style      int                `json:"style,omitempty"`
+	Label      string             `json:"label,omitempty"`
+	CustomID   string             `json:"custom_id,omitempty"`
+	Components []DiscordComponent `json:"components,omitempty"`
+}
+
+// DiscordHandler answers Discord's interactions: PING, the /dickbutt
+// command with its place option, and the reroll button on its messages.
+func DiscordHandler(res http.ResponseWriter, req *http.Request) {
+	if discordPublicKey == nil {
+		http.NotFound(res, req)
+		return
+	}
+	body, err := verifyDiscordRequest(req, discordPublicKey)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var in DiscordInteraction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var out DiscordResponse
+	switch in.Type {
+	case discordPing:
+		out.Type = discordPong
+	case discordCommand:
+		out = discordDeferred(&in, discordDeferredMessage, func() DiscordResponse {
+			return discordPlaceResponse(req, discordMessage, in.option("place"))
+		})
+	case discordComponent:
+		place, ok := strings.CutPrefix(in.Data.CustomID, discordRerollPrefix)
+		if !ok {
+			http.Error(res, "unknown component", http.StatusBadRequest)
+			return
+		}
+		out = discordDeferred(&in, discordDeferredUpdate, func() DiscordResponse {
+			return discordPlaceResponse(req, discordUpdateMessage, place)
+		})
+	default:
+		http.Error(res, "unknown interaction type", http.StatusBadRequest)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(res).Encode(out); err != nil {
+		log.Printf("discord: %v", err)
+	}
+}
+
+// discordDeferred answers an interaction with respond's response, unless
+// that takes longer than discordDeadline.  Then Discord is told to wait,
+// with a response of deferredType, and the message is edited in once it's
+// ready.
+func discordDeferred(in *DiscordInteraction, deferredType int, respond func() DiscordResponse) DiscordResponse {
+	done := make(chan DiscordResponse, 1)
+	go func() {
+		done <- respond()
+	}()
+
+	select {
+	case out := <-done:
+		return out
+	case <-time.After(discordDeadline):
+		go func() {
+			editDiscordOriginal(in, (<-done).Data)
+		}()
+		return DiscordResponse{Type: deferredType}
+	}
+}
+
+// editDiscordOriginal replaces the message an interaction responded with
+// by data, through the interaction's webhook.
+func editDiscordOriginal(in *DiscordInteraction, data *DiscordResponseData) {
+	if in.ApplicationID == "" || in.Token == "" {
+		log.Printf("discord: can't follow up an interaction without its application_id and token")
+		return
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("discord: %v", err)
+		return
+	}
+
+	u := discordAPI + "/webhooks/" + url.PathEscape(in.ApplicationID) + "/" + url.PathEscape(in.Token) + "/messages/@original"
+	req, err := http.NewRequest("PATCH", u, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("discord: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := discordClient.Do(req)
+	if err != nil {
+		log.Printf("discord: editing the response: %v", err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		log.Printf("discord: editing the response: %v", res.Status)
+	}
+}
+
+// verifyDiscordRequest checks req's Ed25519 signature, of its timestamp and
+// body, and returns the body.
+func verifyDiscordRequest(req *http.Request, key ed25519.PublicKey) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	sig, err := hex.DecodeString(req.Header.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, errDiscordSignature
+	}
+	msg := append([]byte(req.Header.Get("X-Signature-Timestamp")), body...)
+	if !ed25519.Verify(key, msg, sig) {
+		return nil, errDiscordSignature
+	}
+	return body, nil
+}
+
+// discordPlaceResponse makes a new page for the place asked for and shows
+// it as an embed of its composite, with a button to reroll it.  Only the
+// user who asked sees the usage message, when there's no place.
+func discordPlaceResponse(req *http.Request, responseType int, requested string) DiscordResponse {
+	places := CanonicalMashup(requested)
+	if len(places) == 0 {
+		return DiscordResponse{Type: discordMessage, Data: &DiscordResponseData{
+			Content: "Usage: /dickbutt place:<place>, or several places joined with +",
+			Flags:   discordEphemeral,
+		}}
+	}
+	place := mashupPlace(places)
+	placeStats.Record(place)
+
+	p := newPlacePage(req, places)
+	page, image := pagePermalink(p)
+	embed := DiscordEmbed{
+		Title:  place + " dickbutt",
+		URL:    absURL(req, page),
+		Image:  &DiscordEmbedImage{URL: absURL(req, image), Width: p.Width, Height: p.Height},
+		Footer: &DiscordEmbedFooter{Text: "dickbutt.in"},
+	}
+	if p.Permalink != "" {
+		embed.Description = discordCredit(p.Title, p.Author, p.Permalink, p.Points)
+	}
+	for _, panel := range p.Panels {
+		if panel.Permalink != "" {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{
+				Name:   panel.Place,
+				Value:  discordCredit(panel.Title, panel.Author, panel.Permalink, panel.Points),
+				Inline: true,
+			})
+		}
+	}
+
+	data := &DiscordResponseData{Embeds: []DiscordEmbed{embed}}
+	// A custom_id only holds 100 bytes, which the longest mashups don't
+	// fit in; those can't be rerolled.
+	if customID := discordRerollPrefix + place; len(customID) <= discordMaxCustomID {
+		data.Components = append(data.Components, DiscordComponent{
+			Type: discordActionRow,
+			Components: []DiscordComponent{{
+				Type:     discordButton,
+				Style:    discordSecondaryButton,
+				Label:    "Reroll",
+				CustomID: customID,
+			}},
+		})
+	}
+	return DiscordResponse{Type: responseType, Data: data}
+}
+
+// discordCredit credits a background in Discord's markdown.
+func discordCredit(title, author, permalink string, points int) string {
+	if title == "" {
+		title = "Untitled"
+	}
+	credit := "[" + discordEscape(title) + "](" + permalink + ")"
+	if author != "" {
+		credit += " by " + discordEscape(author)
+	}
+	return fmt.Sprintf("%s on Imgur, %d points", credit, points)
+}
+
+// discordEscape escapes Discord's markdown.
+func discordEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "[", `\[`, "]", `\]`, "|", `\|`, ">", `\>`).Replace(s)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	discordPingFixture    = `{"id":"1","application_id":"2","type":1,"token":"t","version":1}`
+	discordCommandFixture = `{"id":"1","application_id":"2","type":2,"token":"t","version":1,"data":{"id":"3","name":"dickbutt","type":1,"options":[{"name":"place","type":3,"value":"Funny Cats"}]}}`
+	discordRerollFixture  = `{"id":"1","application_id":"2","type":3,"token":"t","version":1,"data":{"custom_id":"reroll:funny-cats","component_type":2}}`
+)
+
+var (
+	// discordKey signs the fixtures, as Discord would.
+	discordKey ed25519.PrivateKey
+
+	// discordImgur stands in for the Imgur API.
+	discordImgur *testImgur
+)
+
+// discordTestSetup points the Imgur client at a testImgur, and has
+// DiscordHandler trust a freshly made key.
+func discordTestSetup() {
+	discordImgur = newTestImgur()
+
+	var public ed25519.PublicKey
+	public, discordKey, _ = ed25519.GenerateKey(nil)
+	discordPublicKey = public
+}
+
+func discordTestTeardown() {
+	discordImgur.Close()
+	discordPublicKey = nil
+}
+
+// discordRequest makes a request of body signed with key.
+func discordRequest(key ed25519.PrivateKey, body string) *http.Request {
+	const timestamp = "1700000000"
+	req := httptest.NewRequest("POST", "http://dickbutt.in/integrations/discord", bytes.NewBufferString(body))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	return req
+}
+
+// serveDiscord runs req through DiscordHandler and decodes the response.
+func serveDiscord(t *testing.T, req *http.Request) DiscordResponse {
+	rec := httptest.NewRecorder()
+	DiscordHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("DiscordHandler returned status %v: %s", rec.Code, rec.Body)
+	}
+
+	var res DiscordResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Decoding response returned error: %v", err)
+	}
+	return res
+}
+
+func TestDiscordPing(t *testing.T) {
+	discordTestSetup()
+	defer discordTestTeardown()
+
+	res := serveDiscord(t, discordRequest(discordKey, discordPingFixture))
+	if res.Type != discordPong {
+		t.Errorf("DiscordHandler returned type %+v, want %+v", res.Type, discordPong)
+	}
+}
+
+func TestDiscordBadSignature(t *testing.T) {
+	discordTestSetup()
+	defer discordTestTeardown()
+
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	for _, req := range []*http.Request{
+		discordRequest(otherKey, discordPingFixture),
+		httptest.NewRequest("POST", "/integrations/discord", strings.NewReader(discordPingFixture)),
+	} {
+		rec := httptest.NewRecorder()
+		DiscordHandler(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("DiscordHandler returned status %v, want %v", rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// A signature over a different body doesn't carry over.
+	req := discordRequest(discordKey, discordPingFixture)
+	req.Body = io.NopCloser(strings.NewReader(discordCommandFixture))
+	rec := httptest.NewRecorder()
+	DiscordHandler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("DiscordHandler returned status %v for a tampered body, want %v", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestDiscordCommand(t *testing.T) {
+	discordTestSetup()
+	defer discordTestTeardown()
+
+	res := serveDiscord(t, discordRequest(discordKey, discordCommandFixture))
+	if res.Type != discordMessage {
+		t.Errorf("DiscordHandler returned type %+v, want %+v", res.Type, discordMessage)
+	}
+	if res.Data == nil || len(res.Data.Embeds) != 1 {
+		t.Fatalf("DiscordHandler returned data %+v, want one embed", res.Data)
+	}
+
+	embed := res.Data.Embeds[0]
+	if want := "funny-cats dickbutt"; embed.Title != want {
+		t.Errorf("Embed title is %+v, want %+v", embed.Title, want)
+	}
+	if embed.Image == nil || !strings.HasPrefix(embed.Image.URL, "http://dickbutt.in"+permalinkPath) || !strings.HasSuffix(embed.Image.URL, ".png") {
+		t.Errorf("Embed image is %+v, want a permalinked composite", embed.Image)
+	}
+	if embed.Image != nil && (embed.Image.Width != 61 || embed.Image.Height != 67) {
+		t.Errorf("Embed image is %vx%v, want 61x67", embed.Image.Width, embed.Image.Height)
+	}
+	want := "[Of course, but maybe...](http://imgur.com/gallery/zHQ2rzI) by kJerAFK on Imgur, 25 points"
+	if embed.Description != want {
+		t.Errorf("Embed description is %+v, want %+v", embed.Description, want)
+	}
+
+	// The composite shows the background the embed credits.
+	token := strings.TrimSuffix(strings.TrimPrefix(embed.Image.URL, "http://dickbutt.in"+permalinkPath), ".png")
+	p, err := pageFromToken(httptest.NewRequest("GET", "/", nil), token)
+	if err != nil {
+		t.Fatalf("pageFromToken returned error: %v", err)
+	}
+	if p.Title != "Of course, but maybe..." || p.Author != "kJerAFK" || p.Points != 25 {
+		t.Errorf("Composite credits %q by %q, %v points, want the background's", p.Title, p.Author, p.Points)
+	}
+	if want := discordImgur.URL + "/zHQ2rzI.png"; p.ImgurSource != want {
+		t.Errorf("Composite background is %+v, want %+v", p.ImgurSource, want)
+	}
+
+	if len(res.Data.Components) != 1 || len(res.Data.Components[0].Components) != 1 {
+		t.Fatalf("DiscordHandler returned components %+v, want one button", res.Data.Components)
+	}
+	if got, want := res.Data.Components[0].Components[0].CustomID, "reroll:funny-cats"; got != want {
+		t.Errorf("Button custom_id is %+v, want %+v", got, want)
+	}
+}
+
+func TestDiscordReroll(t *testing.T) {
+	discordTestSetup()
+	defer discordTestTeardown()
+
+	res := serveDiscord(t, discordRequest(discordKey, discordRerollFixture))
+	if res.Type != discordUpdateMessage {
+		t.Errorf("DiscordHandler returned type %+v, want %+v", res.Type, discordUpdateMessage)
+	}
+	if res.Data == nil || len(res.Data.Embeds) != 1 || res.Data.Embeds[0].Title != "funny-cats dickbutt" {
+		t.Errorf("DiscordHandler returned data %+v, want the funny-cats embed", res.Data)
+	}
+}
+
+func TestDiscordDeferred(t *testing.T) {
+	discordTestSetup()
+	defer discordTestTeardown()
+	defer func(d time.Duration) { discordDeadline = d }(discordDeadline)
+	discordDeadline = time.Millisecond
+	discordImgur.SetDelay(100 * time.Millisecond)
+
+	type edit struct {
+		method, url string
+		data        DiscordResponseData
+	}
+	edits := make(chan edit, 2)
+	defer func(c *http.Client) { discordClient = c }(discordClient)
+	discordClient = &http.Client{Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+		e := edit{method: req.Method, url: req.URL.String()}
+		json.NewDecoder(req.Body).Decode(&e.data)
+		edits <- e
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+	})}
+
+	for _, tt := range []struct {
+		fixture string
+		want    int
+	}{
+		{discordCommandFixture, discordDeferredMessage},
+		{discordRerollFixture, discordDeferredUpdate},
+	} {
+		res := serveDiscord(t, discordRequest(discordKey, tt.fixture))
+		if res.Type != tt.want || res.Data != nil {
+			t.Errorf("DiscordHandler returned %+v, want a deferred response of type %v", res, tt.want)
+		}
+
+		select {
+		case e := <-edits:
+			if e.method != "PATCH" || e.url != discordAPI+"/webhooks/2/t/messages/@original" {
+				t.Errorf("Follow-up was %v %v, want an edit of the original response", e.method, e.url)
+			}
+			if len(e.data.Embeds) != 1 || e.data.Embeds[0].Title != "funny-cats dickbutt" {
+				t.Errorf("Follow-up was %+v, want the funny-cats embed", e.data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The response was never followed up")
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"strings"
+)
+
+// A filter changes an image in place.  Each costs about the same per pixel
+// whatever it's asked to do, and a filtered composite is at most
+// filterMaxSize pixels either way, so filtering one costs at most so much.
+type filter func(img *image.RGBA)
+
+var filters = map[string]filter{
+	"grayscale": grayscale,
+	"sepia":     sepia,
+	"invert":    invert,
+	"pixelate":  pixelate,
+	"blur":      blur,
+	"deepfry":   deepFry,
+	"flare":     lensFlare,
+}
+
+// What a filter is applied to is given by a prefix on its name; without
+// one, it's the background.
+const (
+	filterOverlay = "overlay:" // the overlay
+	filterAll     = "all:"     // the whole composite, captions and all
+)
+
+const (
+	// maxFilters is the most filters applied to a composite.
+	maxFilters = 6
+
+	// filterMaxSize is the most pixels across either way a filtered
+	// composite is.
+	filterMaxSize = 1600
+)
+
+// filterChain is the filters applied to a composite's background, overlay
+// and the whole composite, in order.
+type filterChain struct {
+	background, overlay, all []filter
+}
+
+// parseFilters returns the filters asked for by ?filter=, a comma
+// separated list applied in order, as they're kept in a Page: lower case,
+// without any it doesn't know, and at most maxFilters of them.
+func parseFilters(s string) string {
+	var names []string
+	for _, name := range strings.Split(strings.ToLower(s), ",") {
+		name = strings.TrimSpace(name)
+		_, ok := filters[strings.TrimPrefix(strings.TrimPrefix(name, filterOverlay), filterAll)]
+		if ok && len(names) < maxFilters {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// pageFilters returns the filters in p.Filter.
+func pageFilters(p Page) filterChain {
+	var c filterChain
+	if p.Filter == "" {
+		return c
+	}
+	for _, name := range strings.Split(p.Filter, ",") {
+		switch {
+		case strings.HasPrefix(name, filterOverlay):
+			c.overlay = append(c.overlay, filters[strings.TrimPrefix(name, filterOverlay)])
+		case strings.HasPrefix(name, filterAll):
+			c.all = append(c.all, filters[strings.TrimPrefix(name, filterAll)])
+		default:
+			c.background = append(c.background, filters[name])
+		}
+	}
+	return c
+}
+
+func (c filterChain) empty() bool {
+	return len(c.background) == 0 && len(c.overlay) == 0 && len(c.all) == 0
+}
+
+func applyFilters(img *image.RGBA, fs []filter) {
+	for _, f := range fs {
+		if f != nil {
+			f(img)
+		}
+	}
+}
+
+// mapColors applies f to the unpremultiplied color of each pixel that
+// isn't transparent.
+func mapColors(img *image.RGBA, f func(r, g, b float64) (float64, float64, float64)) {
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		a := float64(img.Pix[i+3])
+		if a == 0 {
+			continue
+		}
+		k := 255 / a
+		r, g, b := f(float64(img.Pix[i])*k, float64(img.Pix[i+1])*k, float64(img.Pix[i+2])*k)
+		img.Pix[i] = uint8(clamp(r, 0, 255) / k)
+		img.Pix[i+1] = uint8(clamp(g, 0, 255) / k)
+		img.Pix[i+2] = uint8(clamp(b, 0, 255) / k)
+	}
+}
+
+func luma(r, g, b float64) float64 {
+	return 0.299*r + 0.587*g + 0.114*b
+}
+
+func grayscale(img *image.RGBA) {
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		y := luma(r, g, b)
+		return y, y, y
+	})
+}
+
+func sepia(img *image.RGBA) {
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		return 0.393*r + 0.769*g + 0.189*b,
+			0.349*r + 0.686*g + 0.168*b,
+			0.272*r + 0.534*g + 0.131*b
+	})
+}
+
+func invert(img *image.RGBA) {
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		return 255 - r, 255 - g, 255 - b
+	})
+}
+
+// pixelate averages blocks of about a 64th of the image's width.
+func pixelate(img *image.RGBA) {
+	b := img.Bounds()
+	size := max(2, b.Dx()/64)
+	for y0 := b.Min.Y; y0 < b.Max.Y; y0 += size {
+		for x0 := b.Min.X; x0 < b.Max.X; x0 += size {
+			block := image.Rect(x0, y0, x0+size, y0+size).Intersect(b)
+
+			var sum [4]int
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				row := img.Pix[img.PixOffset(block.Min.X, y):img.PixOffset(block.Max.X, y)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0], sum[1], sum[2], sum[3] = sum[0]+int(row[i]), sum[1]+int(row[i+1]), sum[2]+int(row[i+2]), sum[3]+int(row[i+3])
+				}
+			}
+			n := block.Dx() * block.Dy()
+			avg := color.RGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), uint8(sum[3] / n)}
+			draw.Draw(img, block, image.NewUniform(avg), image.Point{}, draw.Src)
+		}
+	}
+}
+
+// blur blurs by about a 200th of the image's width, with three box blurs
+// each way, which come close to a Gaussian blur and cost the same whatever
+// their radius.
+func blur(img *image.RGBA) {
+	b := img.Bounds()
+	r := max(1, b.Dx()/200)
+	tmp := make([]uint8, len(img.Pix))
+	for pass := 0; pass < 3; pass++ {
+		// Along the rows, then down the columns.
+		for y := 0; y < b.Dy(); y++ {
+			boxBlur(tmp, img.Pix, y*img.Stride, 4, b.Dx(), r)
+		}
+		copy(img.Pix, tmp)
+		for x := 0; x < b.Dx(); x++ {
+			boxBlur(tmp, img.Pix, x*4, img.Stride, b.Dy(), r)
+		}
+		copy(img.Pix, tmp)
+	}
+}
+
+// boxBlur sets each of the n pixels of src, from start and step bytes
+// apart, in dst to the average of those within r of it, with a running
+// sum.  Pixels past the ends repeat the ones at the ends.
+func boxBlur(dst, src []uint8, start, step, n, r int) {
+	at := func(i int) int {
+		return start + min(max(i, 0), n-1)*step
+	}
+	for c := 0; c < 4; c++ {
+		sum := 0
+		for i := -r; i <= r; i++ {
+			sum += int(src[at(i)+c])
+		}
+		for i := 0; i < n; i++ {
+			dst[start+i*step+c] = uint8(sum / (2*r + 1))
+			sum += int(src[at(i+r+1)+c]) - int(src[at(i-r)+c])
+		}
+	}
+}
+
+// deepFry boosts the saturation and contrast, then crushes the image with
+// the worst JPEG compression there is.
+func deepFry(img *image.RGBA) {
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		y := luma(r, g, b)
+		boost := func(v float64) float64 {
+			v = y + (v-y)*2.5             // saturation
+			return (v-128)*1.4 + 128 + 10 // contrast, and a little brighter
+		}
+		return boost(r), boost(g) * 0.95, boost(b) * 0.8
+	})
+
+	// JPEG has no alpha, so it's given the colors unpremultiplied, and the
+	// image keeps its own alpha.
+	opaque := image.NewRGBA(img.Bounds())
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		if a := uint32(img.Pix[i+3]); a > 0 {
+			opaque.Pix[i] = uint8(uint32(img.Pix[i]) * 0xff / a)
+			opaque.Pix[i+1] = uint8(uint32(img.Pix[i+1]) * 0xff / a)
+			opaque.Pix[i+2] = uint8(uint32(img.Pix[i+2]) * 0xff / a)
+		}
+		opaque.Pix[i+3] = 0xff
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, opaque, &jpeg.Options{Quality: 4}); err != nil {
+		return
+	}
+	crushed, err := jpeg.Decode(&buf)
+	if err != nil {
+		return
+	}
+
+	draw.Draw(opaque, opaque.Bounds(), crushed, crushed.Bounds().Min, draw.Src)
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		a := uint32(img.Pix[i+3])
+		img.Pix[i] = uint8(uint32(opaque.Pix[i]) * a / 0xff)
+		img.Pix[i+1] = uint8(uint32(opaque.Pix[i+1]) * a / 0xff)
+		img.Pix[i+2] = uint8(uint32(opaque.Pix[i+2]) * a / 0xff)
+	}
+}
+
+// lensFlare adds a lens flare from the top right: a glow where the light
+// is, and rings and a streak along the line through the middle from it.
+func lensFlare(img *image.RGBA) {
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	sx, sy := w*0.8, h*0.2 // the light
+	cx, cy := w/2, h/2
+
+	type glow struct {
+		x, y, radius float64
+		r, g, bl     float64 // brightness of each channel at the middle
+		ring         bool    // brightest at its edge rather than its middle
+	}
+	glows := []glow{{sx, sy, w * 0.25, 255, 240, 200, false}}
+	for _, g := range []struct{ at, radius, r, g, b float64 }{
+		{0.4, 0.03, 120, 200, 255},
+		{0.8, 0.05, 200, 255, 150},
+		{1.3, 0.02, 255, 180, 120},
+		{1.7, 0.09, 120, 150, 255},
+	} {
+		glows = append(glows, glow{sx + (cx-sx)*g.at, sy + (cy-sy)*g.at, w * g.radius, g.r * 0.35, g.g * 0.35, g.b * 0.35, true})
+	}
+
+	for _, g := range glows {
+		rect := image.Rect(int(g.x-g.radius), int(g.y-g.radius), int(g.x+g.radius)+1, int(g.y+g.radius)+1).Intersect(b)
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				d := math.Hypot(float64(x)-g.x, float64(y)-g.y) / g.radius
+				if d >= 1 {
+					continue
+				}
+				k := (1 - d) * (1 - d)
+				if g.ring {
+					k = 0.3 + 0.7*math.Pow(d, 4)
+				}
+				addLight(img, x, y, g.r*k, g.g*k, g.bl*k)
+			}
+		}
+	}
+
+	// A streak across, through the light.
+	for y := max(b.Min.Y, int(sy-h*0.01)); y < min(b.Max.Y, int(sy+h*0.01)+1); y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			k := math.Exp(-math.Abs(float64(x)-sx)/(w*0.15)) * (1 - math.Abs(float64(y)-sy)/(h*0.01+1))
+			addLight(img, x, y, 200*k, 210*k, 255*k)
+		}
+	}
+}
+
+// addLight adds light to a pixel, as if it shone onto it.
+func addLight(img *image.RGBA, x, y int, r, g, b float64) {
+	i := img.PixOffset(x, y)
+	a := float64(img.Pix[i+3]) / 255
+	img.Pix[i] = uint8(clamp(float64(img.Pix[i])+r*a, 0, 255))
+	img.Pix[i+1] = uint8(clamp(float64(img.Pix[i+1])+g*a, 0, 255))
+	img.Pix[i+2] = uint8(clamp(float64(img.Pix[i+2])+b*a, 0, 255))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseFilters(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"", ""},
+		{"sepia", "sepia"},
+		{" Sepia, OVERLAY:invert ,all:deepfry", "sepia,overlay:invert,all:deepfry"},
+		{"sepia,sparkle,overlay:,all:flare", "sepia,all:flare"},
+		{"blur,blur,blur,blur,blur,blur,blur,blur", "blur,blur,blur,blur,blur,blur"},
+	} {
+		if got := parseFilters(tt.in); got != tt.want {
+			t.Errorf("parseFilters(%q) returned %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPageFilters(t *testing.T) {
+	c := pageFilters(Page{Filter: "sepia,overlay:invert,all:deepfry,all:flare"})
+	if len(c.background) != 1 || len(c.overlay) != 1 || len(c.all) != 2 {
+		t.Errorf("pageFilters returned %v, %v and %v filters, want 1, 1 and 2", len(c.background), len(c.overlay), len(c.all))
+	}
+	if !pageFilters(Page{}).empty() {
+		t.Errorf("pageFilters of no filters returned some")
+	}
+}
+
+// filterTestImage is a gradient with a transparent corner.
+func filterTestImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			if x < 8 && y < 8 {
+				continue
+			}
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 0x80, 0xff})
+		}
+	}
+	return img
+}
+
+func TestFilters(t *testing.T) {
+	for name, f := range filters {
+		img := filterTestImage()
+		f(img)
+		// Filters change the colors, but leave what's transparent alone.
+		if img.RGBAAt(0, 0) != (color.RGBA{}) {
+			t.Errorf("%v left a transparent pixel %v", name, img.RGBAAt(0, 0))
+		}
+		if bytes.Equal(img.Pix, filterTestImage().Pix) {
+			t.Errorf("%v left the image as it was", name)
+		}
+	}
+}
+
+func TestInvertTwice(t *testing.T) {
+	img := filterTestImage()
+	invert(img)
+	invert(img)
+	if !bytes.Equal(img.Pix, filterTestImage().Pix) {
+		t.Errorf("Inverting twice changed the image")
+	}
+}
+
+func TestBlurUniform(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 20))
+	for i := range img.Pix {
+		img.Pix[i] = 0x60
+	}
+	blur(img)
+	for i, v := range img.Pix {
+		if v != 0x60 {
+			t.Fatalf("Blurring a uniform image changed byte %v to %#x", i, v)
+		}
+	}
+}
+
+func TestCompositeFilterSize(t *testing.T) {
+	srv := backgroundServer(t, 2400, 1200)
+
+	p := Page{ImgurSource: srv.URL + "/zHQ2rzI.png", Top: 40, Bottom: 30, Overlay: defaultOverlay}
+	img, err := Composite(p)
+	if err != nil {
+		t.Fatalf("Composite returned error: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 2400 {
+		t.Errorf("Composite without filters returned %vx%v, want 2400 wide", b.Dx(), b.Dy())
+	}
+
+	p.Filter = "grayscale,all:blur"
+	img, err = Composite(p)
+	if err != nil {
+		t.Fatalf("Composite returned error: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != filterMaxSize || b.Dy() != 800 {
+		t.Errorf("Composite with filters returned %vx%v, want %vx800", b.Dx(), b.Dy(), filterMaxSize)
+	}
+}
+
+func TestDeepFryAlpha(t *testing.T) {
+	// Half transparent gray, premultiplied, deep fries to the same color
+	// as opaque gray does, at half the alpha.
+	half := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	opaque := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < len(half.Pix); i += 4 {
+		copy(half.Pix[i:], []uint8{0x40, 0x40, 0x40, 0x80})
+		copy(opaque.Pix[i:], []uint8{0x80, 0x80, 0x80, 0xff})
+	}
+	deepFry(half)
+	deepFry(opaque)
+
+	for i := 0; i < len(half.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			want := int(opaque.Pix[i+c]) * 0x80 / 0xff
+			if d := int(half.Pix[i+c]) - want; d < -2 || d > 2 {
+				t.Fatalf("Deep frying half transparent gray gave %v, want about %v", half.Pix[i:i+4], want)
+			}
+		}
+	}
+}
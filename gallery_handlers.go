@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"net/http"
+)
+
+// Imgur's gallery sorts and windows; anything else in ?sort= or ?window=
+// is ignored in favour of the gallery's own default.
+var (
+	gallerySorts   = map[string]bool{"viral": true, "top": true, "time": true, "rising": true}
+	galleryWindows = map[string]bool{"day": true, "week": true, "month": true, "year": true, "all": true}
+)
+
+// galleryParams returns the gallery sort and window requested through the
+// query string.
+func galleryParams(req *http.Request) (sort, window string) {
+	q := req.URL.Query()
+	if s := q.Get("sort"); gallerySorts[s] {
+		sort = s
+	}
+	if w := q.Get("window"); galleryWindows[w] {
+		window = w
+	}
+	return
+}
+
+// TagHandler is DickButtHandler for the gallery of a tag.
+func TagHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	sort, window := galleryParams(req)
+	renderPage(res, req, NewPage(req, vars["tag"], TagSearcher(vars["tag"], sort, window)))
+}
+
+// SubredditHandler is DickButtHandler for a subreddit's gallery.
+func SubredditHandler(res http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	sort, window := galleryParams(req)
+	renderPage(res, req, NewPage(req, vars["subreddit"], SubredditSearcher(vars["subreddit"], sort, window)))
+}
+
+// MemesHandler is DickButtHandler for the memes gallery.
+func MemesHandler(res http.ResponseWriter, req *http.Request) {
+	sort, window := galleryParams(req)
+	renderPage(res, req, NewPage(req, "memes", MemesSearcher(sort, window)))
+}
+
+// HotHandler is DickButtHandler for the front page gallery.
+func HotHandler(res http.ResponseWriter, req *http.Request) {
+	sort, window := galleryParams(req)
+	renderPage(res, req, NewPage(req, "hot", HotSearcher(sort, window)))
+}
+
+// RandomHandler is DickButtHandler for Imgur's random gallery.
+func RandomHandler(res http.ResponseWriter, req *http.Request) {
+	renderPage(res, req, NewPage(req, "random", RandomSearcher()))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGalleryParams(t *testing.T) {
+	for _, tt := range []struct {
+		query, sort, window string
+	}{
+		{"", "", ""},
+		{"?sort=top&window=all", "top", "all"},
+		{"?sort=rising", "rising", ""},
+		{"?window=day", "", "day"},
+		{"?sort=best&window=decade", "", ""},
+		{"?sort=TOP&window=Week", "", ""},
+	} {
+		sort, window := galleryParams(httptest.NewRequest("GET", "/hot"+tt.query, nil))
+		if sort != tt.sort || window != tt.window {
+			t.Errorf("galleryParams for %q returned %q, %q, want %q, %q", tt.query, sort, window, tt.sort, tt.window)
+		}
+	}
+}
+
+func TestGalleryRoutes(t *testing.T) {
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{"/t/cats?sort=top&window=all", "/3/gallery/t/cats/top/all/0"},
+		{"/r/pics", "/3/gallery/r/pics/time/week/0"},
+		{"/r/pics%3Fsort=top", "/3/gallery/r/pics%3Fsort=top/time/week/0"},
+		{"/memes?sort=best", "/3/gallery/g/memes/viral/week/0"},
+		{"/hot?window=month", "/3/gallery/hot/viral/month/0"},
+		{"/random", "/3/gallery/random/random/0"},
+		// Anything else is a place, and searched for.
+		{"/cats", "/3/gallery/search/top/0"},
+	} {
+		func() {
+			srv := newTestImgur()
+			defer srv.Close()
+
+			req := httptest.NewRequest("GET", "http://dickbutt.in"+tt.path, nil)
+			req.Header.Set("Accept", "text/html")
+			res := httptest.NewRecorder()
+			setupRouter().ServeHTTP(res, req)
+			if res.Code != http.StatusOK {
+				t.Errorf("%v returned %v, want %v", tt.path, res.Code, http.StatusOK)
+			}
+
+			requests := srv.Requests()
+			if len(requests) != 1 || requests[0].URL.EscapedPath() != tt.want {
+				var got []string
+				for _, r := range requests {
+					got = append(got, r.URL.EscapedPath())
+				}
+				t.Errorf("%v requested %v from Imgur, want %v", tt.path, got, tt.want)
+			}
+		}()
+	}
+}
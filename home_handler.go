@@ -5,6 +5,36 @@ import (
 	"net/http"
 )
 
+type HomePage struct {
+	Trending []string
+	Recent   []string
+	Overlay  string
+}
+
+// HomeHandler serves the landing page to browsers, and a line of
+// instructions to everyone else.
 func HomeHandler(res http.ResponseWriter, req *http.Request) {
-	fmt.Fprintln(res, "Please goto http://www.dickbutt.in/[whatever you want goes here] for some dick butt fun\nI use Imgur api to grab images, credits to come.")
+	res.Header().Add("Vary", "Accept")
+	if negotiate(req.Header.Get("Accept"), []string{"text/plain", "text/html"}) != "text/html" {
+		fmt.Fprintln(res, "Please goto "+absURL(req, "/")+"[whatever you want goes here] for some dick butt fun\nI use Imgur api to grab images, credits to come.")
+		return
+	}
+
+	p := HomePage{
+		Trending: placeStats.Trending(10),
+		Recent:   placeStats.Recent(10),
+		Overlay:  requestOverlay(req),
+	}
+	executeTemplate(res, req, "home", p)
+}
+
+// SearchHandler sends the home page's search form on to the place searched
+// for.
+func SearchHandler(res http.ResponseWriter, req *http.Request) {
+	q := CanonicalPlace(req.FormValue("q"))
+	if q == "" {
+		http.Redirect(res, req, "/", http.StatusFound)
+		return
+	}
+	http.Redirect(res, req, "/"+placePath(q), http.StatusFound)
 }
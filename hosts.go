@@ -0,0 +1,129 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// defaultOverlay is the asset drawn over backgrounds on hosts without one of
+// their own.
+const defaultOverlay = "dickbutt.png"
+
+// siteDomains are the domains whose subdomains are places, as in
+// cats.dickbutt.in, from SITE_DOMAINS, a comma separated list.
+var siteDomains = parseList(os.Getenv("SITE_DOMAINS"))
+
+// themeHosts maps hosts to the theme they're shown in, from THEME_HOSTS,
+// and overlayHosts to the asset used as their overlay, from OVERLAY_HOSTS;
+// both comma separated lists of host=value pairs.  A setting for one of
+// siteDomains covers its subdomains too.
+var (
+	themeHosts   = parseHostMap(os.Getenv("THEME_HOSTS"))
+	overlayHosts = mustParseOverlayHosts(os.Getenv("OVERLAY_HOSTS"))
+)
+
+func parseList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseHostMap(s string) map[string]string {
+	hosts := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) == 2 {
+			hosts[strings.ToLower(kv[0])] = kv[1]
+		}
+	}
+	return hosts
+}
+
+// mustParseOverlayHosts parses OVERLAY_HOSTS, making sure each overlay is
+// an asset, so a typo is caught at startup rather than on every page.
+func mustParseOverlayHosts(s string) map[string]string {
+	hosts := parseHostMap(s)
+	for host, overlay := range hosts {
+		if _, ok := assets.byName[overlay]; !ok {
+			log.Fatalf("OVERLAY_HOSTS: no asset %q for %s", overlay, host)
+		}
+	}
+	return hosts
+}
+
+// setupSubdomainRoutes adds a route serving the place named by the
+// subdomain of each of siteDomains.  It must come before the route for the
+// home page, which every other path on those hosts falls through to.
+func setupSubdomainRoutes(r *mux.Router) {
+	for _, domain := range siteDomains {
+		r.Host("{subdomain:[a-z0-9-]+}." + domain).Path("/").HandlerFunc(SubdomainHandler)
+	}
+}
+
+// SubdomainHandler serves the place a subdomain names, in whichever format
+// the client asks for by Accept header.  www and the reserved names are the
+// home page.
+func SubdomainHandler(res http.ResponseWriter, req *http.Request) {
+	label := mux.Vars(req)["subdomain"]
+	place := CanonicalPlace(label)
+	if label == "www" || place == "" || isReservedPlace(label) {
+		HomeHandler(res, req)
+		return
+	}
+	_, format := placeFormat(res, req, "")
+	servePlace(res, req, []string{place}, format)
+}
+
+// requestHost returns the host req was made to, without a port.
+func requestHost(req *http.Request) string {
+	host := strings.ToLower(req.Host)
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	return host
+}
+
+// hostSetting looks up the setting in hosts for the host req was made to,
+// or for the site domain it's a subdomain of.
+func hostSetting(hosts map[string]string, req *http.Request) (string, bool) {
+	host := requestHost(req)
+	if v, ok := hosts[host]; ok {
+		return v, true
+	}
+	for _, domain := range siteDomains {
+		if strings.HasSuffix(host, "."+domain) {
+			v, ok := hosts[domain]
+			return v, ok
+		}
+	}
+	return "", false
+}
+
+// requestOverlay returns the overlay to draw for req.
+func requestOverlay(req *http.Request) string {
+	if overlay, ok := hostSetting(overlayHosts, req); ok {
+		return overlay
+	}
+	return defaultOverlay
+}
+
+// isOverlay reports whether name is an overlay used by some host.
+func isOverlay(name string) bool {
+	if name == defaultOverlay {
+		return true
+	}
+	for _, overlay := range overlayHosts {
+		if overlay == name {
+			return true
+		}
+	}
+	return false
+}
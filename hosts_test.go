@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withSiteDomains runs f with siteDomains set to domains.
+func withSiteDomains(domains []string, f func()) {
+	defer func(old []string) { siteDomains = old }(siteDomains)
+	siteDomains = domains
+	f()
+}
+
+func TestHostSetting(t *testing.T) {
+	hosts := parseHostMap("dickbutt.in=site, Other.Example=other, cats.dickbutt.in=cats")
+	withSiteDomains([]string{"dickbutt.in"}, func() {
+		for _, tt := range []struct {
+			host, want string
+			ok         bool
+		}{
+			{"dickbutt.in", "site", true},
+			{"dogs.dickbutt.in:8080", "site", true},
+			{"Cats.Dickbutt.in", "cats", true},
+			{"other.example", "other", true},
+			{"notdickbutt.in", "", false},
+			{"dickbutt.in.evil.example", "", false},
+		} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Host = tt.host
+			if got, ok := hostSetting(hosts, req); got != tt.want || ok != tt.ok {
+				t.Errorf("hostSetting for %v returned %q, %v, want %q, %v", tt.host, got, ok, tt.want, tt.ok)
+			}
+		}
+	})
+}
+
+func TestSubdomainRoutes(t *testing.T) {
+	srv := newTestImgur()
+	defer srv.Close()
+
+	withSiteDomains([]string{"dickbutt.in"}, func() {
+		r := setupRouter()
+		serve := func(url, accept string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", url, nil)
+			req.Header.Set("Accept", accept)
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, req)
+			return res
+		}
+
+		var data PageData
+		res := serve("http://funny-cats.dickbutt.in/", "application/json")
+		if err := json.Unmarshal(res.Body.Bytes(), &data); err != nil || data.Place != "funny-cats" {
+			t.Errorf("funny-cats.dickbutt.in returned %v %s, want the funny-cats page", res.Code, res.Body)
+		}
+
+		// www and the site itself are the home page.
+		for _, url := range []string{"http://www.dickbutt.in/", "http://dickbutt.in/"} {
+			if res := serve(url, "text/plain"); !strings.HasPrefix(res.Body.String(), "Please goto") {
+				t.Errorf("%v returned %v %s, want the home page", url, res.Code, res.Body)
+			}
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ImageCache is a size-limited, least recently used cache of images on
+// local disk.  Each image is kept as two files in Dir: the image itself and
+// a ".meta" file with the headers it's served with.
+type ImageCache struct {
+	Dir      string
+	MaxBytes int64 // The most the cache may hold in total
+	MaxItem  int64 // The largest single image the cache will hold
+
+	mu    sync.Mutex
+	size  int64
+	lru   *list.List // of *cacheEntry, most recently used first
+	index map[string]*list.Element
+}
+
+// CacheMeta is what's remembered about a cached image besides its bytes.
+type CacheMeta struct {
+	ContentType string
+	ETag        string
+	Size        int64
+}
+
+type cacheEntry struct {
+	key string
+	CacheMeta
+}
+
+// NewImageCache returns a cache in dir, picking up any images left there by
+// a previous run.
+func NewImageCache(dir string, maxBytes, maxItem int64) (*ImageCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &ImageCache{
+		Dir:      dir,
+		MaxBytes: maxBytes,
+		MaxItem:  maxItem,
+		lru:      list.New(),
+		index:    make(map[string]*list.Element),
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Oldest first, so the most recently written end up at the front.
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".tmp-") {
+			os.Remove(c.path(f.Name()))
+			continue
+		}
+		key := strings.TrimSuffix(f.Name(), ".meta")
+		if key == f.Name() {
+			continue
+		}
+		meta, err := c.readMeta(key)
+		if err != nil {
+			c.remove(key)
+			continue
+		}
+		c.add(key, meta)
+	}
+	c.evict()
+
+	return c, nil
+}
+
+func (c *ImageCache) path(key string) string {
+	return filepath.Join(c.Dir, key)
+}
+
+func (c *ImageCache) readMeta(key string) (CacheMeta, error) {
+	var meta CacheMeta
+	data, err := ioutil.ReadFile(c.path(key) + ".meta")
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, err
+	}
+	if fi, err := os.Stat(c.path(key)); err != nil || fi.Size() != meta.Size {
+		return meta, os.ErrNotExist
+	}
+	return meta, nil
+}
+
+// Get opens the cached image for key, marking it recently used.  The caller
+// must close the returned file.
+func (c *ImageCache) Get(key string) (*os.File, CacheMeta, bool) {
+	c.mu.Lock()
+	e, ok := c.index[key]
+	if ok {
+		c.lru.MoveToFront(e)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, CacheMeta{}, false
+	}
+
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		c.mu.Lock()
+		c.unlink(key)
+		c.mu.Unlock()
+		return nil, CacheMeta{}, false
+	}
+	return f, e.Value.(*cacheEntry).CacheMeta, true
+}
+
+// Create returns a writer for a new image to be stored under key.  Nothing
+// is cached until Commit is called on it.
+func (c *ImageCache) Create(key string) (*CacheWriter, error) {
+	f, err := ioutil.TempFile(c.Dir, ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	return &CacheWriter{cache: c, key: key, f: f}, nil
+}
+
+// CacheWriter writes an image into the cache.  If more than the cache's
+// MaxItem is written, the image is silently dropped rather than cached.
+type CacheWriter struct {
+	cache *ImageCache
+	key   string
+	f     *os.File
+	n     int64
+	err   error
+}
+
+func (w *CacheWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		// Keep accepting writes so a tee'd response isn't interrupted.
+		return len(p), nil
+	}
+	w.n += int64(len(p))
+	if w.n > w.cache.MaxItem {
+		w.err = errTooLarge
+		return len(p), nil
+	}
+	if _, err := w.f.Write(p); err != nil {
+		w.err = err
+	}
+	return len(p), nil
+}
+
+// Commit stores the written image with meta, evicting older images to make
+// room for it.
+func (w *CacheWriter) Commit(meta CacheMeta) error {
+	defer os.Remove(w.f.Name())
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	if w.err != nil {
+		return w.err
+	}
+
+	meta.Size = w.n
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	c := w.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := ioutil.WriteFile(c.path(w.key)+".meta", data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(w.f.Name(), c.path(w.key)); err != nil {
+		return err
+	}
+
+	c.unlink(w.key)
+	c.add(w.key, meta)
+	c.evict()
+	return nil
+}
+
+// Abort throws away whatever has been written.
+func (w *CacheWriter) Abort() {
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
+
+// add indexes an image already on disk.  The caller must hold mu, except
+// while the cache is being built.
+func (c *ImageCache) add(key string, meta CacheMeta) {
+	c.index[key] = c.lru.PushFront(&cacheEntry{key: key, CacheMeta: meta})
+	c.size += meta.Size
+}
+
+// unlink drops key from the index without touching the disk.  The caller
+// must hold mu.
+func (c *ImageCache) unlink(key string) {
+	if e, ok := c.index[key]; ok {
+		c.size -= e.Value.(*cacheEntry).Size
+		c.lru.Remove(e)
+		delete(c.index, key)
+	}
+}
+
+// remove deletes key's files.
+func (c *ImageCache) remove(key string) {
+	os.Remove(c.path(key))
+	os.Remove(c.path(key) + ".meta")
+}
+
+// evict removes the least recently used images until the cache fits in
+// MaxBytes.  The caller must hold mu.
+func (c *ImageCache) evict() {
+	for c.size > c.MaxBytes && c.lru.Len() > 0 {
+		key := c.lru.Back().Value.(*cacheEntry).key
+		c.unlink(key)
+		c.remove(key)
+	}
+}
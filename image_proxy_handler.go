@@ -0,0 +1,192 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imgurMediaURL is where proxied images are fetched from; always https,
+// whatever the gallery links say.
+const imgurMediaURL = "https://i.imgur.com/"
+
+// Defaults for the image cache, overridden by IMG_CACHE_DIR and
+// IMG_CACHE_MAX_BYTES.
+const defaultImageCacheBytes = 512 << 20
+
+var errTooLarge = errors.New("image too large to cache")
+
+// imageIDPattern matches the names the proxy will serve: an Imgur image id,
+// with an optional size suffix, and an extension.
+var imageIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{5,12}\.(jpg|jpeg|png|gif|webp|mp4|webm)$`)
+
+var imageCache = newImageCache()
+
+var proxyClient = &http.Client{Timeout: 30 * time.Second}
+
+func newImageCache() *ImageCache {
+	dir := os.Getenv("IMG_CACHE_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "dickbutt-img")
+	}
+
+	maxBytes := int64(defaultImageCacheBytes)
+	if s := os.Getenv("IMG_CACHE_MAX_BYTES"); s != "" {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			maxBytes = n
+		}
+	}
+
+	c, err := NewImageCache(dir, maxBytes, maxImageBytes)
+	if err != nil {
+		log.Printf("image cache disabled: %v", err)
+		return nil
+	}
+	return c
+}
+
+// proxyURL returns the URL the page should load an Imgur image through, or
+// link unchanged if it isn't something the proxy serves.
+func proxyURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host != "i.imgur.com" {
+		return link
+	}
+	name := strings.TrimPrefix(u.Path, "/")
+	if !imageIDPattern.MatchString(name) {
+		return link
+	}
+	return "/img/" + name
+}
+
+// ImageProxyHandler serves Imgur media from the local cache, fetching and
+// caching it on a miss, so visitors never talk to Imgur directly.
+func ImageProxyHandler(res http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["id"]
+	if !imageIDPattern.MatchString(name) {
+		http.NotFound(res, req)
+		return
+	}
+
+	if imageCache != nil {
+		if f, meta, ok := imageCache.Get(name); ok {
+			defer f.Close()
+			serveCached(res, req, f, meta)
+			return
+		}
+	}
+
+	upstream, err := proxyClient.Get(imgurMediaURL + name)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Body.Close()
+
+	contentType := upstream.Header.Get("Content-Type")
+	if upstream.StatusCode != http.StatusOK || !isMedia(contentType) {
+		http.Error(res, "image unavailable", http.StatusBadGateway)
+		return
+	}
+	if upstream.ContentLength > maxImageBytes {
+		http.Error(res, "image too large", http.StatusBadGateway)
+		return
+	}
+
+	// Imgur's ETag is stable for an image, so it's ours too.  Failing that,
+	// the image is read in whole first, so the hash of its bytes can be.
+	etag := upstream.Header.Get("ETag")
+	var body io.Reader = io.LimitReader(upstream.Body, maxImageBytes)
+	length := upstream.ContentLength
+	if etag == "" {
+		data, err := io.ReadAll(io.LimitReader(upstream.Body, maxImageBytes+1))
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if len(data) > maxImageBytes {
+			http.Error(res, "image too large", http.StatusBadGateway)
+			return
+		}
+		sum := sha1.Sum(data)
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+		body, length = bytes.NewReader(data), int64(len(data))
+	}
+
+	// A removed image redirects to a placeholder, which mustn't be cached
+	// under the removed image's name, here or downstream.
+	exact := upstream.Request.URL.Path == "/"+name
+	var out io.Writer = res
+	var cw *CacheWriter
+	if imageCache != nil && exact {
+		if cw, err = imageCache.Create(name); err == nil {
+			out = io.MultiWriter(res, cw)
+		}
+	}
+
+	h := res.Header()
+	h.Set("Content-Type", contentType)
+	if exact {
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	h.Set("ETag", etag)
+	if length >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+
+	// A body that breaks off, or goes on past maxImageBytes, which is only
+	// found out after the headers have gone, mustn't look like a whole
+	// image to whoever caches it downstream: the connection is dropped
+	// rather than the response ended.
+	_, err = io.Copy(out, body)
+	if err == nil {
+		if n, _ := io.CopyN(io.Discard, upstream.Body, 1); n > 0 {
+			err = fmt.Errorf("more than %d bytes", maxImageBytes)
+		}
+	}
+	if err != nil {
+		if cw != nil {
+			cw.Abort()
+		}
+		log.Printf("proxying %s: %v", name, err)
+		panic(http.ErrAbortHandler)
+	}
+	if cw == nil {
+		return
+	}
+	if err := cw.Commit(CacheMeta{ContentType: contentType, ETag: etag}); err != nil && err != errTooLarge {
+		log.Printf("caching %s: %v", name, err)
+	}
+}
+
+// serveCached serves an image from the cache, answering conditional and
+// range requests.
+func serveCached(res http.ResponseWriter, req *http.Request, f *os.File, meta CacheMeta) {
+	h := res.Header()
+	h.Set("Content-Type", meta.ContentType)
+	h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	if meta.ETag != "" {
+		h.Set("ETag", meta.ETag)
+	}
+	http.ServeContent(res, req, "", time.Time{}, f)
+}
+
+func isMedia(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// roundTripper answers the proxy's requests to Imgur itself.
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// zeros reads as many zero bytes as asked for.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// proxyTestSetup makes the proxy fetch images as body returns them, with
+// etag if it isn't empty and without a Content-Length, and cache them in a
+// directory of their own.  The returned func puts things back as they were.
+func proxyTestSetup(t *testing.T, etag string, body func() io.Reader) func() {
+	oldClient, oldCache := proxyClient, imageCache
+	proxyClient = &http.Client{Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{"Content-Type": {"image/png"}}
+		if etag != "" {
+			header.Set("ETag", etag)
+		}
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        header,
+			Body:          io.NopCloser(body()),
+			ContentLength: -1,
+			Request:       req,
+		}, nil
+	})}
+	cache, err := NewImageCache(t.TempDir(), 1<<30, 1<<30)
+	if err != nil {
+		t.Fatalf("NewImageCache returned error: %v", err)
+	}
+	imageCache = cache
+	return func() {
+		proxyClient, imageCache = oldClient, oldCache
+	}
+}
+
+// proxy requests name through ImageProxyHandler, reporting whether it
+// dropped the connection.
+func proxy(name string) (res *httptest.ResponseRecorder, aborted bool) {
+	res = httptest.NewRecorder()
+	r := mux.NewRouter()
+	r.HandleFunc("/img/{id}", ImageProxyHandler)
+	defer func() {
+		if r := recover(); r != nil {
+			if r != http.ErrAbortHandler {
+				panic(r)
+			}
+			aborted = true
+		}
+	}()
+	r.ServeHTTP(res, httptest.NewRequest("GET", "/img/"+name, nil))
+	return res, false
+}
+
+func TestImageProxyCaches(t *testing.T) {
+	// The SHA-1 of "png", for when Imgur doesn't send an ETag.
+	const sum = `"9040a7d6cdf7a0d6cab1823831c6ceb7d01af97f"`
+
+	for _, upstream := range []string{`"imgur"`, ""} {
+		func() {
+			defer proxyTestSetup(t, upstream, func() io.Reader { return strings.NewReader("png") })()
+
+			want := upstream
+			if want == "" {
+				want = sum
+			}
+			for _, hit := range []bool{false, true} {
+				res, aborted := proxy("zHQ2rzI.png")
+				if aborted || res.Code != http.StatusOK || res.Body.String() != "png" {
+					t.Fatalf("Proxying returned %v %q, aborted %v, want 200 png", res.Code, res.Body, aborted)
+				}
+				h := res.Header()
+				if h.Get("ETag") != want || h.Get("Cache-Control") != "public, max-age=31536000, immutable" {
+					t.Errorf("Proxying with upstream ETag %q, cached %v, returned ETag %q and Cache-Control %q, want ETag %q, immutable",
+						upstream, hit, h.Get("ETag"), h.Get("Cache-Control"), want)
+				}
+			}
+			if f, meta, ok := imageCache.Get("zHQ2rzI.png"); !ok || meta.Size != 3 {
+				t.Errorf("Cache has %+v, %v, want the image", meta, ok)
+			} else {
+				f.Close()
+			}
+		}()
+	}
+}
+
+func TestImageProxyPlaceholder(t *testing.T) {
+	defer proxyTestSetup(t, "", nil)()
+	// A removed image redirects to Imgur's placeholder.
+	proxyClient = &http.Client{Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+		removed, _ := http.NewRequest("GET", imgurMediaURL+"removed.png", nil)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"image/png"}},
+			Body:       io.NopCloser(strings.NewReader("removed")),
+			Request:    removed,
+		}, nil
+	})}
+
+	res, _ := proxy("zHQ2rzI.png")
+	if res.Code != http.StatusOK || res.Header().Get("ETag") == "" {
+		t.Errorf("Proxying a removed image returned %v with ETag %q, want 200 with an ETag", res.Code, res.Header().Get("ETag"))
+	}
+	if cc := res.Header().Get("Cache-Control"); strings.Contains(cc, "immutable") || strings.Contains(cc, "max-age") {
+		t.Errorf("Proxying a removed image returned Cache-Control %q, want it revalidated", cc)
+	}
+	if _, _, ok := imageCache.Get("zHQ2rzI.png"); ok {
+		t.Errorf("Proxying a removed image cached the placeholder")
+	}
+}
+
+func TestImageProxyTooLarge(t *testing.T) {
+	tooLarge := func() io.Reader { return io.LimitReader(zeros{}, maxImageBytes+1) }
+
+	// With Imgur's ETag the image is streamed, so it's only found too
+	// large after the headers have gone.
+	func() {
+		defer proxyTestSetup(t, `"imgur"`, tooLarge)()
+		if _, aborted := proxy("zHQ2rzI.png"); !aborted {
+			t.Errorf("Proxying more than maxImageBytes finished the response, want it aborted")
+		}
+		if _, _, ok := imageCache.Get("zHQ2rzI.png"); ok {
+			t.Errorf("Proxying more than maxImageBytes cached the image")
+		}
+	}()
+
+	// Without, it's read in whole before answering.
+	func() {
+		defer proxyTestSetup(t, "", tooLarge)()
+		if res, aborted := proxy("zHQ2rzI.png"); aborted || res.Code != http.StatusBadGateway {
+			t.Errorf("Proxying more than maxImageBytes without an ETag returned %v, aborted %v, want %v", res.Code, aborted, http.StatusBadGateway)
+		}
+		if _, _, ok := imageCache.Get("zHQ2rzI.png"); ok {
+			t.Errorf("Proxying more than maxImageBytes cached the image")
+		}
+	}()
+}
+
+func TestProxyURL(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"https://i.imgur.com/zHQ2rzI.png", "/img/zHQ2rzI.png"},
+		{"http://i.imgur.com/zHQ2rzIl.jpg", "/img/zHQ2rzIl.jpg"},
+		{"https://i.imgur.com/a/zHQ2rzI.png", "https://i.imgur.com/a/zHQ2rzI.png"},
+		{"https://imgur.com/zHQ2rzI.png", "https://imgur.com/zHQ2rzI.png"},
+		{"https://i.imgur.com/zHQ2rzI.exe", "https://i.imgur.com/zHQ2rzI.exe"},
+	} {
+		if got := proxyURL(tt.in); got != tt.want {
+			t.Errorf("proxyURL(%v) returned %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -5,47 +5,202 @@ import (
 
 	"math/rand"
 	"os"
+	"sync"
 )
 
 var clientID = os.Getenv("IMGUR_CLIENT_ID")
 var clientSecret = os.Getenv("IMGUR_SECRET_ID")
 
-var client = imgur.NewClient(nil, clientID, clientSecret)
+var client = newImgurClient()
 
-func ImgurSearcher(image string) (url string) {
+// newImgurClient returns an anonymous client unless a team account has been
+// authorized, by IMGUR_REFRESH_TOKEN and/or a token kept in IMGUR_TOKEN_FILE.
+// Imgur rotates refresh tokens, so IMGUR_TOKEN_FILE is where the latest one
+// is kept across restarts; IMGUR_REFRESH_TOKEN only seeds it.
+//
+// The account only makes the app's searches and lookups count against its
+// own rate limits; shares are uploaded anonymously, see shareClient.
+func newImgurClient() *imgur.Client {
+	c := imgur.NewClient(nil, clientID, clientSecret)
+
+	var store imgur.TokenStore = &imgur.MemoryTokenStore{}
+	if path := os.Getenv("IMGUR_TOKEN_FILE"); path != "" {
+		store = &imgur.FileTokenStore{Path: path}
+	}
+
+	if refresh := os.Getenv("IMGUR_REFRESH_TOKEN"); refresh != "" {
+		if t, err := store.Token(); err == nil && t == nil {
+			store.SetToken(&imgur.Token{RefreshToken: refresh})
+		}
+	}
+
+	c.SetTokenStore(store)
+	return c
+}
+
+// maxCachedAlbums bounds albumCache; search results are mostly albums, so
+// without a limit the cache would grow with every new search term.
+const maxCachedAlbums = 512
+
+// albumCache remembers the images of albums we've already looked up, since
+// gallery search results don't include them and each lookup costs credits.
+var albumCache = struct {
+	sync.Mutex
+	images map[string][]imgur.Image
+}{images: make(map[string][]imgur.Image)}
+
+// overCapacity is shown when Imgur has nothing for us.
+const overCapacity = "https://s.imgur.com/images/OverCapacity_700.png"
+
+// Background is the image shown behind the overlay, along with what's
+// needed to credit whoever posted it.
+type Background struct {
+	ID        string // The ID of the gallery item it came from
+	Link      string // The direct link to the image
+	Width     int    // The width of the image in pixels, 0 if unknown
+	Height    int    // The height of the image in pixels, 0 if unknown
+	Animated  bool   // Whether the image is animated
+	Title     string // The title of the gallery item it came from
+	Author    string // The poster's username, empty if anonymous
+	Permalink string // The gallery item's page on Imgur
+	Points    int    // Upvotes minus downvotes
+}
+
+func ImgurSearcher(image string) Background {
 	results, err := client.Gallery.Search(image, "top", 0)
+	return pickBackground(results, err)
+}
 
+// TagSearcher picks a background from the gallery of a tag.
+func TagSearcher(tag, sort, window string) Background {
+	gallery, err := client.Gallery.Tag(tag, sort, window, 0)
 	if err != nil {
-		url = "http://s.imgur.com/images/OverCapacity_700.png"
-		return
+		return pickBackground(nil, err)
 	}
+	return pickBackground(gallery.Items, nil)
+}
+
+// SubredditSearcher picks a background from a subreddit's gallery.
+func SubredditSearcher(subreddit, sort, window string) Background {
+	results, err := client.Gallery.Subreddit(subreddit, sort, window, 0)
+	return pickBackground(results, err)
+}
+
+// MemesSearcher picks a background from the memes gallery.
+func MemesSearcher(sort, window string) Background {
+	results, err := client.Gallery.Memes(sort, window, 0)
+	return pickBackground(results, err)
+}
+
+// HotSearcher picks a background from the front page gallery.
+func HotSearcher(sort, window string) Background {
+	results, err := client.Gallery.Main("hot", sort, window, 0)
+	return pickBackground(results, err)
+}
 
-	if len(results) <= 0 {
-		url = "http://s.imgur.com/images/OverCapacity_700.png"
+// RandomSearcher picks a background from Imgur's random gallery.
+func RandomSearcher() Background {
+	results, err := client.Gallery.Random(0)
+	return pickBackground(results, err)
+}
+
+// pickBackground picks a random image out of a gallery.
+func pickBackground(results []imgur.GalleryImageAlbum, err error) (bg Background) {
+	if err != nil || len(results) <= 0 {
+		bg.Link = overCapacity
 		return
 	}
 
-	image_index := rand.Intn(len(results))
-	images := results[image_index]
-	if images.IsAlbum {
-		if len(images.Images) > 0 {
-			url = images.Images[0].Link
-		} else {
-			url = getFirstImage(results)
+	item := results[rand.Intn(len(results))]
+	if item.IsAlbum {
+		image := getAlbumImage(item)
+		if image.Link == "" {
+			var ok bool
+			if item, ok = getFirstImage(results); !ok {
+				bg.Link = overCapacity
+				return
+			}
+			image = imgur.Image{Link: item.Link, Width: item.Width, Height: item.Height, Animated: item.Animated}
 		}
+		bg.Link, bg.Width, bg.Height, bg.Animated = image.Link, image.Width, image.Height, image.Animated
 	} else {
-		url = images.Link
+		bg.Link, bg.Width, bg.Height, bg.Animated = item.Link, item.Width, item.Height, item.Animated
 	}
+
+	bg.ID = item.ID
+	bg.Title = item.Title
+	bg.Author = item.AccountUrl
+	bg.Permalink = galleryPermalink(item.ID)
+	bg.Points = item.Ups - item.Downs
 	return
 }
 
-func getFirstImage(images []imgur.GalleryImageAlbum) (url string) {
+func getFirstImage(images []imgur.GalleryImageAlbum) (image imgur.GalleryImageAlbum, ok bool) {
 	for _, image := range images {
 		if !image.IsAlbum {
-			url = image.Link
-			return
+			return image, true
+		}
+	}
+	return
+}
+
+// getAlbumImage picks a random image from an album. Search results usually
+// omit an album's images, so they're fetched (and cached) on demand, falling
+// back to the album cover if the lookup fails.
+func getAlbumImage(album imgur.GalleryImageAlbum) (image imgur.Image) {
+	images := album.Images
+	if len(images) == 0 {
+		images = albumImages(album.ID)
+	}
+
+	if len(images) > 0 {
+		return images[rand.Intn(len(images))]
+	}
+
+	if album.Cover != "" {
+		image.Link = "http://i.imgur.com/" + album.Cover + ".jpg"
+		image.Width = album.CoverWidth
+		image.Height = album.CoverHeight
+	}
+	return
+}
+
+// albumImages returns the images of the album with the given id, asking
+// Imgur only when the album isn't already in albumCache.
+func albumImages(id string) []imgur.Image {
+	albumCache.Lock()
+	images, ok := albumCache.images[id]
+	albumCache.Unlock()
+	if ok {
+		return images
+	}
+
+	images, err := client.Album.Images(id)
+	if err != nil {
+		return nil
+	}
+
+	albumCache.Lock()
+	if len(albumCache.images) >= maxCachedAlbums {
+		albumCache.images = make(map[string][]imgur.Image)
+	}
+	albumCache.images[id] = images
+	albumCache.Unlock()
+	return images
+}
+
+// TopComment returns the best comment on a gallery item and its author, or
+// empty strings if there isn't one.
+func TopComment(id string) (comment, author string) {
+	comments, err := client.Comment.Gallery(id, imgur.CommentSortBest)
+	if err != nil {
+		return
+	}
+
+	for _, c := range comments {
+		if !c.Deleted && c.Comment != "" {
+			return c.Comment, c.Author
 		}
 	}
-	url = "http://s.imgur.com/images/OverCapacity_700.png"
 	return
 }
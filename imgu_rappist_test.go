@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bitbucket.org/liamstask/go-imgur/imgur"
+
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testGallerySearchResponse is the one result a testImgur finds, an image
+// it serves itself.
+const testGallerySearchResponse = `{"data":[{"id":"zHQ2rzI","title":"Of course, but maybe...","link":"%s\/zHQ2rzI.png","width":61,"height":67,"animated":false,"account_url":"kJerAFK","ups":27,"downs":2,"is_album":false}],"success":true,"status":200}`
+
+// testImgur stands in for the Imgur API and its images.
+type testImgur struct {
+	*httptest.Server
+
+	oldClient *imgur.Client
+
+	mu       sync.Mutex
+	requests []*http.Request
+	delay    time.Duration // how long the API takes to answer
+}
+
+// newTestImgur starts a testImgur and points client at it, allowing
+// composites to fetch its image.  Close puts things back as they were.
+func newTestImgur() *testImgur {
+	s := &testImgur{oldClient: client}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/zHQ2rzI.png", func(w http.ResponseWriter, r *http.Request) {
+		png.Encode(w, image.NewRGBA(image.Rect(0, 0, 61, 67)))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		delay := s.delay
+		s.mu.Unlock()
+		time.Sleep(delay)
+		fmt.Fprintf(w, testGallerySearchResponse, s.URL)
+	})
+	s.Server = httptest.NewServer(mux)
+
+	u, _ := url.Parse(s.URL)
+	imgurHosts[u.Host] = true
+	client = imgur.NewClient(nil, "clientID", "clientSecret")
+	client.BaseURL, _ = url.Parse(s.URL + "/3/")
+	return s
+}
+
+// SetDelay makes the API take d to answer.
+func (s *testImgur) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// Requests returns the API requests made so far.
+func (s *testImgur) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request(nil), s.requests...)
+}
+
+func (s *testImgur) Close() {
+	u, _ := url.Parse(s.URL)
+	delete(imgurHosts, u.Host)
+	client = s.oldClient
+	s.Server.Close()
+}
+
+func TestImgurSearcher(t *testing.T) {
+	srv := newTestImgur()
+	defer srv.Close()
+
+	bg := ImgurSearcher("funny cats & dogs")
+	if bg.ID != "zHQ2rzI" || bg.Link != srv.URL+"/zHQ2rzI.png" {
+		t.Errorf("ImgurSearcher returned %+v, want zHQ2rzI", bg)
+	}
+	requests := srv.Requests()
+	if len(requests) != 1 || requests[0].URL.Query().Get("q") != "funny cats & dogs" {
+		t.Errorf("ImgurSearcher made requests %v, want one search for funny cats & dogs", requests)
+	}
+}
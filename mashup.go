@@ -0,0 +1,299 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The ways a mashup's backgrounds can be laid out, picked by ?layout=.
+const (
+	LayoutSplit    = "split"    // side by side
+	LayoutDiagonal = "diagonal" // in slanted bands
+	LayoutGrid     = "grid"     // in rows of two or more
+)
+
+var mashupLayouts = map[string]bool{
+	LayoutSplit:    true,
+	LayoutDiagonal: true,
+	LayoutGrid:     true,
+}
+
+// mashupSize is the size a mashup is composited at; each background is
+// cropped to fill its panel.
+var mashupSize = image.Pt(1200, 800)
+
+// Panel is one place's background in a mashup.
+type Panel struct {
+	Place            string
+	ImgurSource      string
+	Background       string
+	BackgroundSrcset string
+	Width            int // Of the background; 0 if unknown
+	Height           int
+	Animated         bool
+	ItemID           string
+	Title            string
+	Author           string
+	Permalink        string
+	Points           int
+	Rect             panelRect    // The part of the page the panel covers
+	Clip             []panelPoint // A convex polygon the panel is cut to, if not all of Rect
+}
+
+// panelPoint and panelRect are in fractions of the page's width and height.
+type panelPoint struct{ X, Y float64 }
+
+type panelRect struct{ X0, Y0, X1, Y1 float64 }
+
+// Style positions the panel on the page.
+func (p Panel) Style() template.CSS {
+	r := p.Rect
+	style := fmt.Sprintf("left:%.4g%%; top:%.4g%%; width:%.4g%%; height:%.4g%%",
+		r.X0*100, r.Y0*100, (r.X1-r.X0)*100, (r.Y1-r.Y0)*100)
+	if len(p.Clip) > 0 {
+		points := make([]string, len(p.Clip))
+		for i, pt := range p.Clip {
+			points[i] = fmt.Sprintf("%.4g%% %.4g%%", pt.X*100, pt.Y*100)
+		}
+		style += "; clip-path: polygon(" + strings.Join(points, ", ") + ")"
+	}
+	return template.CSS(style)
+}
+
+// mashupLayout returns the layout asked for by ?layout=, or else the one
+// that suits n panels best.
+func mashupLayout(req *http.Request, n int) string {
+	if layout := req.URL.Query().Get("layout"); mashupLayouts[layout] {
+		return layout
+	}
+	if n == 4 {
+		return LayoutGrid
+	}
+	return LayoutSplit
+}
+
+// NewMashupPage searches for a background for each of places and lays them
+// out in panels, with the overlay straddling a seam between two of them.
+func NewMashupPage(req *http.Request, places []string, layout string) Page {
+	panels := layoutPanels(layout, len(places))
+
+	var wg sync.WaitGroup
+	for i, place := range places {
+		wg.Add(1)
+		go func(i int, place string) {
+			defer wg.Done()
+			bg := ImgurSearcher(placeQuery(place))
+			panels[i].Place = place
+			panels[i].ImgurSource = bg.Link
+			panels[i].Background, panels[i].BackgroundSrcset = sizeBackground(req, bg)
+			panels[i].Width, panels[i].Height, panels[i].Animated = bg.Width, bg.Height, bg.Animated
+			panels[i].ItemID = bg.ID
+			panels[i].Title = bg.Title
+			panels[i].Author = bg.Author
+			panels[i].Permalink = bg.Permalink
+			panels[i].Points = bg.Points
+		}(i, place)
+	}
+	wg.Wait()
+
+	top, left := seamOverlay(layout, len(places))
+	return Page{
+		Width:   mashupSize.X,
+		Height:  mashupSize.Y,
+		Top:     top,
+		Bottom:  left,
+		Place:   mashupPlace(places),
+		ItemID:  panels[0].ItemID,
+		Overlay: requestOverlay(req),
+		Layout:  layout,
+		Panels:  panels,
+	}
+}
+
+// layoutPanels returns n panels laid out as layout, with nothing in them
+// yet.
+func layoutPanels(layout string, n int) []Panel {
+	panels := make([]Panel, n)
+	fn := float64(n)
+	switch layout {
+	case LayoutDiagonal:
+		// Band i is where x+y is between 2i/n and 2(i+1)/n.
+		for i := range panels {
+			panels[i].Rect = panelRect{0, 0, 1, 1}
+			panels[i].Clip = diagonalBand(2*float64(i)/fn, 2*float64(i+1)/fn)
+		}
+	case LayoutGrid:
+		cols := int(math.Ceil(math.Sqrt(fn)))
+		rows := (n + cols - 1) / cols
+		for i := range panels {
+			row, col := i/cols, i%cols
+			// The last row may be short, so its panels are wider.
+			inRow := cols
+			if row == rows-1 {
+				inRow = n - row*cols
+			}
+			panels[i].Rect = panelRect{
+				float64(col) / float64(inRow), float64(row) / float64(rows),
+				float64(col+1) / float64(inRow), float64(row+1) / float64(rows),
+			}
+		}
+	default:
+		for i := range panels {
+			panels[i].Rect = panelRect{float64(i) / fn, 0, float64(i+1) / fn, 1}
+		}
+	}
+	return panels
+}
+
+// diagonalBand returns the polygon covering the part of the page where x+y
+// is between lo and hi.
+func diagonalBand(lo, hi float64) []panelPoint {
+	page := []panelPoint{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	band := clipPolygon(page, func(p panelPoint) float64 { return p.X + p.Y - lo })
+	return clipPolygon(band, func(p panelPoint) float64 { return hi - p.X - p.Y })
+}
+
+// clipPolygon cuts poly down to where the linear function inside is at
+// least zero.
+func clipPolygon(poly []panelPoint, inside func(panelPoint) float64) []panelPoint {
+	var clipped []panelPoint
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		fa, fb := inside(a), inside(b)
+		if fa >= 0 {
+			clipped = append(clipped, a)
+		}
+		if (fa < 0 && fb > 0) || (fa > 0 && fb < 0) {
+			t := fa / (fa - fb)
+			clipped = append(clipped, panelPoint{a.X + t*(b.X-a.X), a.Y + t*(b.Y-a.Y)})
+		}
+	}
+	return clipped
+}
+
+// seamOverlay picks a random spot for the overlay, as percentages for
+// Page's Top and Bottom, centered on a seam between panels.
+func seamOverlay(layout string, n int) (top, left int) {
+	// The overlay's size as a percentage of the page, taking it to be
+	// square.
+	w := overlayScale * 100
+	h := w * float64(mashupSize.X) / float64(mashupSize.Y)
+
+	var x, y float64
+	switch layout {
+	case LayoutDiagonal:
+		c := 2 * float64(1+rand.Intn(n-1)) / float64(n)
+		x = math.Max(0, c-1) + rand.Float64()*(math.Min(1, c)-math.Max(0, c-1))
+		y = c - x
+	case LayoutGrid:
+		// Where the first row's panels meet the row below.
+		cols := int(math.Ceil(math.Sqrt(float64(n))))
+		rows := (n + cols - 1) / cols
+		x = float64(1+rand.Intn(cols-1)) / float64(cols)
+		y = 1 / float64(rows)
+		if rows == 1 {
+			y = rand.Float64()
+		}
+	default:
+		x = float64(1+rand.Intn(n-1)) / float64(n)
+		y = rand.Float64()
+	}
+
+	left = int(clamp(x*100-w/2, 0, 100-w))
+	top = int(clamp(y*100-h/2, 0, 100-h))
+	return
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// compositePanels draws each panel's background, cropped to fill it.
+func compositePanels(panels []Panel) (*image.RGBA, error) {
+	bgs := make([]image.Image, len(panels))
+	errs := make([]error, len(panels))
+	var wg sync.WaitGroup
+	for i, panel := range panels {
+		wg.Add(1)
+		go func(i int, src string) {
+			defer wg.Done()
+			bgs[i], errs[i] = fetchImage(src)
+		}(i, panel.ImgurSource)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dst := image.NewRGBA(image.Rectangle{Max: mashupSize})
+	for i, panel := range panels {
+		r := image.Rect(
+			int(panel.Rect.X0*float64(mashupSize.X)), int(panel.Rect.Y0*float64(mashupSize.Y)),
+			int(panel.Rect.X1*float64(mashupSize.X)), int(panel.Rect.Y1*float64(mashupSize.Y)),
+		)
+		src := cover(bgs[i], r.Size())
+		if len(panel.Clip) == 0 {
+			draw.ApproxBiLinear.Scale(dst, r, bgs[i], src, draw.Src, nil)
+			continue
+		}
+		scaled := image.NewRGBA(r)
+		draw.ApproxBiLinear.Scale(scaled, r, bgs[i], src, draw.Src, nil)
+		draw.DrawMask(dst, r, scaled, r.Min, &polygonMask{panel.Clip, mashupSize}, r.Min, draw.Over)
+	}
+	return dst, nil
+}
+
+// cover returns the largest part of img, from its middle, with the same
+// proportions as size.
+func cover(img image.Image, size image.Point) image.Rectangle {
+	b := img.Bounds()
+	if size.X == 0 || size.Y == 0 {
+		return b
+	}
+	w, h := b.Dx(), b.Dy()
+	if w*size.Y > h*size.X {
+		w = h * size.X / size.Y
+	} else {
+		h = w * size.Y / size.X
+	}
+	min := b.Min.Add(image.Pt((b.Dx()-w)/2, (b.Dy()-h)/2))
+	return image.Rectangle{min, min.Add(image.Pt(w, h))}
+}
+
+// polygonMask is opaque inside a convex polygon on a page of the given
+// size, and transparent outside.
+type polygonMask struct {
+	poly []panelPoint
+	size image.Point
+}
+
+func (m *polygonMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (m *polygonMask) Bounds() image.Rectangle { return image.Rectangle{Max: m.size} }
+
+func (m *polygonMask) At(x, y int) color.Color {
+	p := panelPoint{(float64(x) + 0.5) / float64(m.size.X), (float64(y) + 0.5) / float64(m.size.Y)}
+	sign := 0.0
+	for i, a := range m.poly {
+		b := m.poly[(i+1)%len(m.poly)]
+		cross := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+		if cross*sign < 0 {
+			return color.Transparent
+		}
+		if cross != 0 {
+			sign = cross
+		}
+	}
+	return color.Opaque
+}
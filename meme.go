@@ -0,0 +1,371 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/norm"
+
+	"image"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// memeMaxRunes is the most meme text kept, along the top or bottom.
+	memeMaxRunes = 120
+
+	// memeMaxLines is the most lines meme text is wrapped onto; text that
+	// doesn't fit them at memeMinSize is cut short.
+	memeMaxLines = 3
+
+	// Meme text is at most memeMaxSize and at least memeMinSize of the
+	// image's width tall, and takes up at most memeMaxHeight of its height,
+	// along the top or bottom.
+	memeMaxSize   = 0.12
+	memeMinSize   = 0.035
+	memeMaxHeight = 0.3
+
+	// memeMargin is the space around meme text, and memeLineHeight the
+	// space between its lines, relative to its size; memeSides is the space
+	// either side of it, relative to the image's width.
+	memeMargin     = 0.25
+	memeLineHeight = 1.1
+	memeSides      = 0.03
+
+	// memeOutline is how thick the outline round the letters is, relative
+	// to their size.
+	memeOutline = 0.07
+
+	// memeMaxWidth bounds the width meme text is drawn at, and so its
+	// outline, which is what costs the most to draw.
+	memeMaxWidth = 1600
+)
+
+// memeNominalWidth is the width meme text is laid out for on the page,
+// where it's sized relative to the window rather than the image.
+const memeNominalWidth = 1000
+
+// memeFont is Go Bold, which the page shows meme text in too, as the
+// go-bold.ttf asset.
+var memeFont = mustParseMemeFont()
+
+func mustParseMemeFont() *sfnt.Font {
+	f, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// MemeCaption is meme text laid out to fit along the top or bottom of an
+// image.
+type MemeCaption struct {
+	Position string // "top" or "bottom"
+	Lines    []string
+	Size     float64 // Font size, as a percentage of the image's width
+}
+
+// requestMeme returns the meme text asked for by ?top= and ?bottom=, if
+// either is.  Meme text along the top is the place's name unless it's
+// given.
+func requestMeme(req *http.Request, place string) (top, bottom string, ok bool) {
+	q := req.URL.Query()
+	_, hasTop := q["top"]
+	_, hasBottom := q["bottom"]
+	if !hasTop && !hasBottom {
+		return "", "", false
+	}
+	top = memeText(q.Get("top"))
+	if top == "" {
+		top = memeText(strings.ReplaceAll(place, "-", " "))
+	}
+	return top, memeText(q.Get("bottom")), true
+}
+
+// memeText tidies text for drawing in memeFont: upper case, at most
+// memeMaxRunes long, and with anything the font has no glyph for spelled
+// some other way.
+func memeText(text string) string {
+	return glyphText(strings.ToUpper(norm.NFKC.String(text)), memeMaxRunes)
+}
+
+// glyphText returns at most maxRunes of text on one line, with anything
+// memeFont has no glyph for spelled some other way if it can be, such as
+// without its accent, or else replaced.
+func glyphText(text string, maxRunes int) string {
+	var buf sfnt.Buffer
+	hasGlyph := func(r rune) bool {
+		i, err := memeFont.GlyphIndex(&buf, r)
+		return err == nil && i != 0
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range text {
+		if n >= maxRunes {
+			break
+		}
+		switch {
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			r = ' '
+		case hasGlyph(r):
+		default:
+			// Try the letter without its marks.
+			base, _ := utf8.DecodeRuneInString(norm.NFD.String(string(r)))
+			switch {
+			case base != r && hasGlyph(base):
+				r = base
+			case unicode.Is(unicode.Mn, r):
+				continue
+			case hasGlyph(utf8.RuneError):
+				r = utf8.RuneError
+			default:
+				r = '?'
+			}
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// MemeCaptions lays out p's meme text as for the page, which shows it at
+// the same size relative to the width as the composite does.
+func (p Page) MemeCaptions() []MemeCaption {
+	if p.TopText == "" && p.BottomText == "" {
+		return nil
+	}
+	w, h := memeNominalWidth, memeNominalWidth*3/4
+	if p.Width > 0 && p.Height > 0 {
+		h = memeNominalWidth * p.Height / p.Width
+	}
+
+	var captions []MemeCaption
+	for _, c := range []struct{ position, text string }{{"top", p.TopText}, {"bottom", p.BottomText}} {
+		if c.text == "" {
+			continue
+		}
+		lines, size := fitMeme(c.text, w, h)
+		captions = append(captions, MemeCaption{c.position, lines, math.Round(size/float64(w)*1000) / 10})
+	}
+	return captions
+}
+
+// fitMeme wraps text at the biggest size that fits it along the top or
+// bottom of a w by h image, returning its lines and their size in pixels.
+func fitMeme(text string, w, h int) ([]string, float64) {
+	maxWidth := fixed.I(int(float64(w) * (1 - 2*memeSides)))
+	minSize := math.Max(8, memeMinSize*float64(w))
+
+	size := math.Max(minSize, math.Min(memeMaxSize*float64(w), memeMaxHeight*float64(h)/memeLineHeight))
+	for {
+		face := memeFace(size)
+		lines, ok := wrapMeme(face, text, maxWidth, memeMaxLines)
+		height := float64(len(lines)) * size * memeLineHeight
+		face.Close()
+		if ok && height <= memeMaxHeight*float64(h) || size <= minSize {
+			return lines, size
+		}
+		size = math.Max(minSize, size*0.9)
+	}
+}
+
+func memeFace(size float64) font.Face {
+	face, err := opentype.NewFace(memeFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		panic(err)
+	}
+	return face
+}
+
+// wrapMeme breaks text into lines no wider than width, splitting on spaces
+// where it can.  It reports whether it all fit onto maxLines; if not, the
+// last line is cut short.
+func wrapMeme(face font.Face, text string, width fixed.Int26_6, maxLines int) ([]string, bool) {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if font.MeasureString(face, candidate) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		// Break words too long for a line of their own.
+		for font.MeasureString(face, word) > width {
+			n := fitRunes(face, word, width)
+			lines = append(lines, word[:n])
+			word = word[n:]
+		}
+		line = word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) <= maxLines {
+		return lines, true
+	}
+	lines = lines[:maxLines]
+	last := lines[maxLines-1]
+	for last != "" && font.MeasureString(face, last+"…") > width {
+		_, size := utf8.DecodeLastRuneInString(last)
+		last = last[:len(last)-size]
+	}
+	lines[maxLines-1] = strings.TrimSpace(last) + "…"
+	return lines, false
+}
+
+// fitRunes returns how many bytes of s fit in width, at least one rune's.
+func fitRunes(face font.Face, s string, width fixed.Int26_6) int {
+	_, n := utf8.DecodeRuneInString(s)
+	for i := range s {
+		if i > n && font.MeasureString(face, s[:i]) > width {
+			break
+		}
+		n = max(n, i)
+	}
+	return n
+}
+
+// drawMeme draws top and bottom along the top and bottom of dst, in white
+// letters outlined in black.  On images wider than memeMaxWidth they're
+// drawn that wide and scaled up, to bound what they cost.
+func drawMeme(dst *image.RGBA, top, bottom string) {
+	if top == "" && bottom == "" {
+		return
+	}
+	b := dst.Bounds()
+	if b.Dx() <= memeMaxWidth {
+		drawMemeText(dst, top, bottom)
+		return
+	}
+	small := image.NewRGBA(image.Rect(0, 0, memeMaxWidth, max(1, b.Dy()*memeMaxWidth/b.Dx())))
+	for _, r := range drawMemeText(small, top, bottom) {
+		scaled := image.Rect(
+			b.Min.X+r.Min.X*b.Dx()/memeMaxWidth, b.Min.Y+r.Min.Y*b.Dx()/memeMaxWidth,
+			b.Min.X+r.Max.X*b.Dx()/memeMaxWidth, b.Min.Y+r.Max.Y*b.Dx()/memeMaxWidth,
+		)
+		draw.ApproxBiLinear.Scale(dst, scaled.Intersect(b), small, r, draw.Over, nil)
+	}
+}
+
+// drawMemeText draws the text for drawMeme, returning where it drew.
+func drawMemeText(dst *image.RGBA, top, bottom string) []image.Rectangle {
+	var drawn []image.Rectangle
+	b := dst.Bounds()
+	for _, c := range []struct {
+		text   string
+		bottom bool
+	}{{top, false}, {bottom, true}} {
+		if c.text == "" {
+			continue
+		}
+		lines, size := fitMeme(c.text, b.Dx(), b.Dy())
+		face := memeFace(size)
+
+		lineH := int(size * memeLineHeight)
+		margin := int(size * memeMargin)
+		y := b.Min.Y + margin
+		if c.bottom {
+			y = b.Max.Y - margin - len(lines)*lineH
+		}
+
+		// The letters are drawn into a mask, which is thickened for the
+		// outline.
+		block := image.Rect(b.Min.X, y, b.Max.X, y+len(lines)*lineH).Inset(-margin).Intersect(b)
+		mask := image.NewAlpha(block)
+		d := &font.Drawer{Dst: mask, Src: image.Opaque, Face: face}
+		ascent := face.Metrics().Ascent
+		for i, line := range lines {
+			x := b.Min.X + (b.Dx()-font.MeasureString(face, line).Round())/2
+			d.Dot = fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y+i*lineH+(lineH-int(size))/2) + ascent}
+			d.DrawString(line)
+		}
+		face.Close()
+
+		outline := dilate(mask, max(1, int(size*memeOutline)))
+		draw.DrawMask(dst, block, image.Black, image.Point{}, outline, block.Min, draw.Over)
+		draw.DrawMask(dst, block, image.White, image.Point{}, mask, block.Min, draw.Over)
+		drawn = append(drawn, block)
+	}
+	return drawn
+}
+
+// dilate returns mask thickened by r pixels all round, which is to say each
+// pixel is the most opaque within r of it.  The disc is taken a row at a
+// time: each of its rows is the most opaque pixel within a half width of
+// it along the row, which costs the same whatever the width.
+func dilate(mask *image.Alpha, r int) *image.Alpha {
+	b := mask.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewAlpha(b)
+	widest := make([]uint8, len(mask.Pix))
+
+	// Rows of the disc the same width apart share widest.
+	byHalfWidth := make(map[int][]int)
+	for dy := -r; dy <= r; dy++ {
+		dx := int(math.Sqrt(float64(r*r - dy*dy)))
+		byHalfWidth[dx] = append(byHalfWidth[dx], dy)
+	}
+	for dx, dys := range byHalfWidth {
+		for y := 0; y < h; y++ {
+			rowMax(widest[y*mask.Stride:y*mask.Stride+w], mask.Pix[y*mask.Stride:y*mask.Stride+w], dx)
+		}
+		for _, dy := range dys {
+			for y := max(0, -dy); y < min(h, h-dy); y++ {
+				src := widest[(y+dy)*mask.Stride:]
+				dst := out.Pix[y*out.Stride:]
+				for x := 0; x < w; x++ {
+					if src[x] > dst[x] {
+						dst[x] = src[x]
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// rowMax sets each of dst to the most of src within r of it.  src is cut
+// into blocks of 2r+1, each scanned both ways, so any window of 2r+1 is
+// the end of one scan and the start of the next (van Herk's algorithm).
+func rowMax(dst, src []uint8, r int) {
+	if r == 0 {
+		copy(dst, src)
+		return
+	}
+	k := 2*r + 1
+	n := (len(src) + 2*r + k - 1) / k * k
+	padded := make([]uint8, n)
+	copy(padded[r:], src)
+
+	forward, backward := make([]uint8, n), make([]uint8, n)
+	for i := 0; i < n; i++ {
+		forward[i] = padded[i]
+		if i%k != 0 && forward[i-1] > forward[i] {
+			forward[i] = forward[i-1]
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		backward[i] = padded[i]
+		if i%k != k-1 && backward[i+1] > backward[i] {
+			backward[i] = backward[i+1]
+		}
+	}
+	for x := range dst {
+		dst[x] = max(backward[x], forward[x+2*r])
+	}
+}
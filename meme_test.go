@@ -0,0 +1,96 @@
+package main
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"image"
+	"math/rand"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMemeText(t *testing.T) {
+	for _, tt := range []struct{ text, want string }{
+		{"one does not simply", "ONE DOES NOT SIMPLY"},
+		{"  spaced\tout\n", "SPACED OUT"},
+		{"crème brûlée", "CRÈME BRÛLÉE"},
+		{"ｆｕｌｌｗｉｄｔｈ 𝐛𝐨𝐥𝐝", "FULLWIDTH BOLD"},
+		{"snowman ☃ 日本", "SNOWMAN � ��"},
+		{strings.Repeat("a", 200), strings.Repeat("A", memeMaxRunes)},
+	} {
+		if got := memeText(tt.text); got != tt.want {
+			t.Errorf("memeText(%q) returned %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRequestMeme(t *testing.T) {
+	for _, tt := range []struct {
+		query, top, bottom string
+		ok                 bool
+	}{
+		{"", "", "", false},
+		{"?top=", "FUNNY CATS", "", true},
+		{"?bottom=so+true", "FUNNY CATS", "SO TRUE", true},
+		{"?top=hello&bottom=there", "HELLO", "THERE", true},
+	} {
+		top, bottom, ok := requestMeme(httptest.NewRequest("GET", "/funny-cats"+tt.query, nil), "funny-cats")
+		if top != tt.top || bottom != tt.bottom || ok != tt.ok {
+			t.Errorf("requestMeme(%q) returned %q, %q, %v, want %q, %q, %v", tt.query, top, bottom, ok, tt.top, tt.bottom, tt.ok)
+		}
+	}
+}
+
+func TestFitMeme(t *testing.T) {
+	for _, text := range []string{
+		"HI",
+		"ONE DOES NOT SIMPLY WALK INTO MORDOR",
+		strings.Repeat("SUPERCALIFRAGILISTIC ", 6),
+	} {
+		lines, size := fitMeme(text, 800, 600)
+		if len(lines) == 0 || len(lines) > memeMaxLines {
+			t.Errorf("fitMeme(%q) returned %v lines, want 1 to %v", text, len(lines), memeMaxLines)
+		}
+		if size < memeMinSize*800 || size > memeMaxSize*800 {
+			t.Errorf("fitMeme(%q) returned size %v, want %v to %v", text, size, memeMinSize*800, memeMaxSize*800)
+		}
+		face := memeFace(size)
+		for _, line := range lines {
+			if w := font.MeasureString(face, line); w > fixed.I(800) {
+				t.Errorf("fitMeme(%q) returned line %q, %v wide, want at most 800", text, line, w.Round())
+			}
+		}
+	}
+}
+
+func TestDilate(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(10, 20, 60, 50))
+	for i := range mask.Pix {
+		if rand.Intn(20) == 0 {
+			mask.Pix[i] = uint8(rand.Intn(256))
+		}
+	}
+
+	for _, r := range []int{1, 3, 7} {
+		got := dilate(mask, r)
+		b := mask.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				// The most opaque pixel within r, the slow way.
+				var want uint8
+				for dy := -r; dy <= r; dy++ {
+					for dx := -r; dx <= r; dx++ {
+						if dx*dx+dy*dy <= r*r && image.Pt(x+dx, y+dy).In(b) {
+							want = max(want, mask.AlphaAt(x+dx, y+dy).A)
+						}
+					}
+				}
+				if a := got.AlphaAt(x, y).A; a != want {
+					t.Fatalf("dilate(%v) at %v,%v is %v, want %v", r, x, y, a, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// placeOffers are the media types a place can be served as, in order of
+// preference when the client likes several equally.
+var placeOffers = []string{"text/html", "image/png", "image/jpeg", "image/gif", "application/json", "text/plain"}
+
+// placeExtensions are the suffixes that pick a place's format explicitly,
+// overriding the Accept header.
+var placeExtensions = map[string]string{
+	".html": "text/html",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".json": "application/json",
+	".txt":  "text/plain",
+}
+
+// placeFormat splits a requested place into the place itself and the media
+// type to serve it as: the one its extension names, or else the best match
+// for the Accept header, in which case Vary is set accordingly.  Command
+// line clients that accept anything get text.  The format is empty if
+// nothing the client accepts is on offer.
+func placeFormat(res http.ResponseWriter, req *http.Request, place string) (string, string) {
+	ext := strings.ToLower(path.Ext(place))
+	if format, ok := placeExtensions[ext]; ok && len(place) > len(ext) {
+		return place[:len(place)-len(ext)], format
+	}
+
+	res.Header().Add("Vary", "Accept, User-Agent")
+	accept := strings.TrimSpace(req.Header.Get("Accept"))
+	if isTerminal(req) && (accept == "" || accept == "*/*") {
+		return place, "text/plain"
+	}
+	return place, negotiate(accept, placeOffers)
+}
+
+// negotiate returns the offer best matching an Accept header, or "" if none
+// is acceptable.  A missing header accepts anything.
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// Offers are in order of preference, so a later one has to be
+		// strictly better liked to win.
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality an Accept header gives a media type,
+// taken from the most specific media range that matches it.
+func acceptQuality(accept, offer string) float64 {
+	offerType, offerSub := splitMediaType(offer)
+
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		rangeType, rangeSub := splitMediaType(params[0])
+
+		// How specific the range is: 2 for type/subtype, 1 for type/*,
+		// 0 for */*.
+		s := -1
+		switch {
+		case rangeType == offerType && rangeSub == offerSub:
+			s = 2
+		case rangeType == offerType && rangeSub == "*":
+			s = 1
+		case rangeType == "*" && rangeSub == "*":
+			s = 0
+		}
+		if s <= specificity {
+			continue
+		}
+
+		rangeQ := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if f, err := strconv.ParseFloat(kv[1], 64); err == nil {
+					rangeQ = f
+				}
+			}
+		}
+		q, specificity = rangeQ, s
+	}
+	return q
+}
+
+func splitMediaType(mediaType string) (string, string) {
+	parts := strings.SplitN(strings.ToLower(strings.TrimSpace(mediaType)), "/", 2)
+	if len(parts) != 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tt := range []struct{ accept, want string }{
+		{"", "text/html"},
+		{"*/*", "text/html"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html"},
+		{"image/avif,image/webp,image/png,image/*;q=0.8", "image/png"},
+		{"image/*", "image/png"},
+		{"image/*;q=0.5, image/jpeg", "image/jpeg"},
+		{"application/json", "application/json"},
+		{"text/*;q=0.5, application/json;q=0.9", "application/json"},
+		{"text/html;q=0, */*", "image/png"},
+		{"application/pdf", ""},
+	} {
+		if got := negotiate(tt.accept, placeOffers); got != tt.want {
+			t.Errorf("negotiate(%q) returned %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceFormat(t *testing.T) {
+	for _, tt := range []struct {
+		place, accept, userAgent string
+		wantPlace, wantFormat    string
+	}{
+		{"cats.png", "text/html", "", "cats", "image/png"},
+		{"cats.JPG", "", "", "cats", "image/jpeg"},
+		{"cats.gif", "", "", "cats", "image/gif"},
+		{"cats.json", "", "", "cats", "application/json"},
+		{"cats", "application/json", "", "cats", "application/json"},
+		{"cats", "*/*", "curl/8.4.0", "cats", "text/plain"},
+		{"cats", "text/html", "curl/8.4.0", "cats", "text/html"},
+		{"cats.com", "", "", "cats.com", "text/html"},
+		{".png", "", "", ".png", "text/html"},
+	} {
+		req := httptest.NewRequest("GET", "/"+tt.place, nil)
+		req.Header.Set("Accept", tt.accept)
+		req.Header.Set("User-Agent", tt.userAgent)
+		place, format := placeFormat(httptest.NewRecorder(), req, tt.place)
+		if place != tt.wantPlace || format != tt.wantFormat {
+			t.Errorf("placeFormat(%q, Accept %q) returned %q, %q, want %q, %q", tt.place, tt.accept, place, format, tt.wantPlace, tt.wantFormat)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OEmbed is an oEmbed response, as in https://oembed.com.
+type OEmbed struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	Title        string `json:"title,omitempty"`
+	AuthorName   string `json:"author_name,omitempty"`
+	AuthorURL    string `json:"author_url,omitempty"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	CacheAge     int    `json:"cache_age,omitempty"`
+	URL          string `json:"url,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+// OEmbedHandler describes a permalink as an oEmbed photo of its composite,
+// which is what the discovery link on every page points at.  A place gets a
+// different background every time, so its url would embed a different
+// picture from the one that was shared; those aren't found.  Only JSON is
+// supported.
+func OEmbedHandler(res http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		http.Error(res, "Only format=json is supported", http.StatusNotImplemented)
+		return
+	}
+
+	u, err := url.Parse(query.Get("url"))
+	if err != nil || u.Path == "" || (u.Host != "" && !strings.EqualFold(u.Host, req.Host)) {
+		http.NotFound(res, req)
+		return
+	}
+
+	token := strings.TrimPrefix(u.Path, permalinkPath)
+	if token == u.Path {
+		http.NotFound(res, req)
+		return
+	}
+	p, err := pageFromToken(req, strings.TrimSuffix(token, ".html"))
+	if err != nil {
+		http.NotFound(res, req)
+		return
+	}
+
+	_, image := pagePermalink(p)
+	embed := OEmbed{
+		Version:      "1.0",
+		Type:         "link",
+		Title:        p.Place + " dickbutt",
+		AuthorName:   p.Author,
+		ProviderName: "dickbutt.in",
+		ProviderURL:  absURL(req, "/"),
+	}
+	if p.Author != "" {
+		embed.AuthorURL = "http://imgur.com/user/" + p.Author
+	}
+
+	// A photo has to say how big it is, which isn't known for every
+	// background.
+	if p.Width > 0 && p.Height > 0 {
+		w, h := fitSize(p.Width, p.Height, query.Get("maxwidth"), query.Get("maxheight"))
+		embed.Type = "photo"
+		embed.URL = absURL(req, image)
+		if w != p.Width {
+			embed.URL += "?w=" + strconv.Itoa(w)
+		}
+		embed.Width, embed.Height = w, h
+		embed.CacheAge = 31536000
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(res).Encode(embed); err != nil {
+		log.Printf("oembed: %v", err)
+	}
+}
+
+// fitSize scales a width and height down to fit within the maximums asked
+// for, if any, keeping their proportions.
+func fitSize(w, h int, maxWidth, maxHeight string) (int, int) {
+	scale := 1.0
+	if mw, err := strconv.Atoi(maxWidth); err == nil && mw > 0 && mw < w {
+		scale = float64(mw) / float64(w)
+	}
+	if mh, err := strconv.Atoi(maxHeight); err == nil && mh > 0 && float64(h)*scale > float64(mh) {
+		scale = float64(mh) / float64(h)
+	}
+	if scale == 1 {
+		return w, h
+	}
+	return max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// permalinkPath is where exact pages are served from.
+const permalinkPath = "/p/"
+
+// pageState is everything needed to show a page again exactly as it was:
+// its backgrounds and who to credit for them, where the overlay went and
+// its caption.  A permalink's token is the state as JSON, signed and base64
+// encoded, so the same page always renders the same picture without
+// anything having to be stored, and nobody else can make one up.
+type pageState struct {
+	Place         string       `json:"p"`
+	Src           string       `json:"s,omitempty"`
+	ItemID        string       `json:"i,omitempty"`
+	Width         int          `json:"w,omitempty"`
+	Height        int          `json:"h,omitempty"`
+	Animated      bool         `json:"an,omitempty"`
+	Title         string       `json:"ti,omitempty"`
+	Author        string       `json:"au,omitempty"`
+	Points        int          `json:"pt,omitempty"`
+	Top           int          `json:"t"`
+	Left          int          `json:"l"`
+	Overlay       string       `json:"o,omitempty"`
+	Caption       string       `json:"c,omitempty"`
+	CaptionAuthor string       `json:"a,omitempty"`
+	TopText       string       `json:"u,omitempty"`
+	BottomText    string       `json:"b,omitempty"`
+	Filter        string       `json:"f,omitempty"`
+	Layout        string       `json:"y,omitempty"`
+	Panels        []panelState `json:"m,omitempty"`
+}
+
+type panelState struct {
+	Place    string `json:"p"`
+	Src      string `json:"s"`
+	ItemID   string `json:"i,omitempty"`
+	Width    int    `json:"w,omitempty"`
+	Height   int    `json:"h,omitempty"`
+	Animated bool   `json:"an,omitempty"`
+	Title    string `json:"ti,omitempty"`
+	Author   string `json:"au,omitempty"`
+	Points   int    `json:"pt,omitempty"`
+}
+
+var errBadPermalink = errors.New("bad permalink")
+
+// permalinkKey signs permalink tokens.  Without PERMALINK_SECRET a key is
+// made up at startup, and permalinks stop working on restart.
+var permalinkKey = newPermalinkKey(os.Getenv("PERMALINK_SECRET"))
+
+func newPermalinkKey(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	log.Println("permalink: PERMALINK_SECRET isn't set, permalinks only last until a restart")
+	return key
+}
+
+// permalinkMACSize is how much of a token's HMAC-SHA256 it carries.
+const permalinkMACSize = 16
+
+// permalinkMAC returns the signature of a token's JSON.
+func permalinkMAC(data []byte) []byte {
+	mac := hmac.New(sha256.New, permalinkKey)
+	mac.Write(data)
+	return mac.Sum(nil)[:permalinkMACSize]
+}
+
+// permalinkToken encodes p's state, after its signature.
+func permalinkToken(p Page) string {
+	s := pageState{
+		Place:         p.Place,
+		Src:           p.ImgurSource,
+		ItemID:        p.ItemID,
+		Width:         p.Width,
+		Height:        p.Height,
+		Animated:      p.Animated,
+		Title:         p.Title,
+		Author:        p.Author,
+		Points:        p.Points,
+		Top:           p.Top,
+		Left:          p.Bottom,
+		Caption:       p.Caption,
+		CaptionAuthor: p.CaptionAuthor,
+		TopText:       p.TopText,
+		BottomText:    p.BottomText,
+		Filter:        p.Filter,
+		Layout:        p.Layout,
+	}
+	if p.Overlay != defaultOverlay {
+		s.Overlay = p.Overlay
+	}
+	for _, panel := range p.Panels {
+		s.Panels = append(s.Panels, panelState{
+			Place:    panel.Place,
+			Src:      panel.ImgurSource,
+			ItemID:   panel.ItemID,
+			Width:    panel.Width,
+			Height:   panel.Height,
+			Animated: panel.Animated,
+			Title:    panel.Title,
+			Author:   panel.Author,
+			Points:   panel.Points,
+		})
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(append(permalinkMAC(data), data...))
+}
+
+// pageFromToken decodes a permalink's token back into the page, with its
+// backgrounds sized for req like NewPage's.  Only backgrounds on Imgur are
+// allowed, and the token must be signed with permalinkKey, so a permalink
+// can't be made to show anything else.
+func pageFromToken(req *http.Request, token string) (Page, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) < permalinkMACSize {
+		return Page{}, errBadPermalink
+	}
+	mac, data := data[:permalinkMACSize], data[permalinkMACSize:]
+	if !hmac.Equal(mac, permalinkMAC(data)) {
+		return Page{}, errBadPermalink
+	}
+	var s pageState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Page{}, errBadPermalink
+	}
+
+	if s.Top < 0 || s.Top > 100 || s.Left < 0 || s.Left > 100 {
+		return Page{}, errBadPermalink
+	}
+	if s.Overlay == "" {
+		s.Overlay = defaultOverlay
+	}
+	if !isOverlay(s.Overlay) {
+		return Page{}, errBadPermalink
+	}
+
+	p := Page{
+		ImgurSource:   s.Src,
+		Width:         s.Width,
+		Height:        s.Height,
+		Animated:      s.Animated,
+		Top:           s.Top,
+		Bottom:        s.Left,
+		Place:         s.Place,
+		ItemID:        s.ItemID,
+		Title:         s.Title,
+		Author:        s.Author,
+		Permalink:     galleryPermalink(s.ItemID),
+		Points:        s.Points,
+		Caption:       s.Caption,
+		CaptionAuthor: s.CaptionAuthor,
+		TopText:       memeText(s.TopText),
+		BottomText:    memeText(s.BottomText),
+		Filter:        parseFilters(s.Filter),
+		Overlay:       s.Overlay,
+	}
+
+	if len(s.Panels) == 0 {
+		if !isImgurURL(s.Src) {
+			return Page{}, errBadPermalink
+		}
+		p.Background, p.BackgroundSrcset = sizeBackground(req, Background{
+			ID: s.ItemID, Link: s.Src, Width: s.Width, Height: s.Height, Animated: s.Animated,
+		})
+		return p, nil
+	}
+
+	if !mashupLayouts[s.Layout] || len(s.Panels) < 2 || len(s.Panels) > maxMashupPlaces {
+		return Page{}, errBadPermalink
+	}
+	p.Layout = s.Layout
+	p.Panels = layoutPanels(s.Layout, len(s.Panels))
+	for i, panel := range s.Panels {
+		if !isImgurURL(panel.Src) {
+			return Page{}, errBadPermalink
+		}
+		p.Panels[i].Place = panel.Place
+		p.Panels[i].ImgurSource = panel.Src
+		p.Panels[i].Background, p.Panels[i].BackgroundSrcset = sizeBackground(req, Background{
+			ID: panel.ItemID, Link: panel.Src, Width: panel.Width, Height: panel.Height, Animated: panel.Animated,
+		})
+		p.Panels[i].Width, p.Panels[i].Height, p.Panels[i].Animated = panel.Width, panel.Height, panel.Animated
+		p.Panels[i].ItemID = panel.ItemID
+		p.Panels[i].Title = panel.Title
+		p.Panels[i].Author = panel.Author
+		p.Panels[i].Permalink = galleryPermalink(panel.ItemID)
+		p.Panels[i].Points = panel.Points
+	}
+	return p, nil
+}
+
+// isImgurURL reports whether link is an image on one of imgurHosts.
+func isImgurURL(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && imgurHosts[u.Host]
+}
+
+// galleryPermalink returns the page on Imgur of the gallery item id.
+func galleryPermalink(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "http://imgur.com/gallery/" + id
+}
+
+// pagePermalink returns the path of p exactly as it is, and of its
+// composite.
+func pagePermalink(p Page) (page, image string) {
+	token := permalinkToken(p)
+	return permalinkPath + token, permalinkPath + token + ".png"
+}
+
+// PermalinkHandler serves a page exactly as it was when its permalink was
+// made, in whichever format the client asks for, like DickButtHandler.
+// Nothing about it can change, so it's cached forever once it's rendered,
+// unless the query asks for something more, such as ?comment, which can.
+func PermalinkHandler(res http.ResponseWriter, req *http.Request) {
+	token, format := placeFormat(res, req, mux.Vars(req)["token"])
+	if format == "" {
+		http.Error(res, "Not Acceptable", http.StatusNotAcceptable)
+		return
+	}
+	p, err := pageFromToken(req, token)
+	if err != nil {
+		http.NotFound(res, req)
+		return
+	}
+
+	if req.URL.RawQuery == "" {
+		res = &immutableWriter{ResponseWriter: res}
+	}
+	render(res, req, p, format)
+}
+
+// immutableWriter marks a response as cacheable forever, but only if it's
+// a success, so an error fetching a background isn't cached for a year.
+type immutableWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *immutableWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status == http.StatusOK {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *immutableWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
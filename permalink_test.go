@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPermalinkRoundTrip(t *testing.T) {
+	plain := Page{
+		ImgurSource:      "https://i.imgur.com/zHQ2rzI.png",
+		Background:       "/img/zHQ2rzI.png",
+		BackgroundSrcset: "/img/zHQ2rzIm.png 287w, /img/zHQ2rzIl.png 574w, /img/zHQ2rzI.png 610w",
+		Width:            610,
+		Height:           679,
+		Top:              40,
+		Bottom:           30,
+		Place:            "funny-cats",
+		ItemID:           "zHQ2rzI",
+		Title:            "Of course, but maybe...",
+		Author:           "kJerAFK",
+		Permalink:        "http://imgur.com/gallery/zHQ2rzI",
+		Points:           25,
+		Caption:          "A comment",
+		CaptionAuthor:    "someone",
+		TopText:          "FUNNY CATS",
+		BottomText:       "EVERYWHERE",
+		Filter:           "sepia,all:flare",
+		Overlay:          defaultOverlay,
+	}
+
+	mashup := Page{Place: "cats+dogs", Top: 50, Bottom: 50, Overlay: defaultOverlay, Layout: LayoutDiagonal}
+	mashup.Panels = layoutPanels(LayoutDiagonal, 2)
+	for i, panel := range []struct{ place, id string }{{"cats", "abcde1"}, {"dogs", "abcde2"}} {
+		mashup.Panels[i].Place = panel.place
+		mashup.Panels[i].ImgurSource = "https://i.imgur.com/" + panel.id + ".jpg"
+		mashup.Panels[i].Background = "/img/" + panel.id + ".jpg"
+		mashup.Panels[i].BackgroundSrcset = "/img/" + panel.id + "m.jpg 320w, /img/" + panel.id + ".jpg 500w"
+		mashup.Panels[i].Width, mashup.Panels[i].Height = 500, 400
+		mashup.Panels[i].ItemID = panel.id
+		mashup.Panels[i].Title = panel.place + " for days"
+		mashup.Panels[i].Author = "someone"
+		mashup.Panels[i].Permalink = "http://imgur.com/gallery/" + panel.id
+		mashup.Panels[i].Points = 7
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, p := range []Page{plain, mashup} {
+		got, err := pageFromToken(req, permalinkToken(p))
+		if err != nil {
+			t.Fatalf("pageFromToken(permalinkToken(%v)) returned error: %v", p.Place, err)
+		}
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("pageFromToken(permalinkToken(p)) returned\n%+v\nwant\n%+v", got, p)
+		}
+	}
+}
+
+func TestPageFromTokenErrors(t *testing.T) {
+	good := Page{ImgurSource: "https://i.imgur.com/zHQ2rzI.png", Place: "cats", Top: 40, Bottom: 30, Overlay: defaultOverlay}
+	bad := func(f func(p *Page)) string {
+		p := good
+		f(&p)
+		return permalinkToken(p)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := pageFromToken(req, permalinkToken(good)); err != nil {
+		t.Fatalf("pageFromToken returned error: %v", err)
+	}
+	unsigned := func(p Page) string {
+		data, _ := json.Marshal(pageState{Place: p.Place, Src: p.ImgurSource, Top: p.Top, Left: p.Bottom})
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	tampered := func(token string) string {
+		data, _ := base64.RawURLEncoding.DecodeString(token)
+		data = bytes.Replace(data, []byte(`"p":"cats"`), []byte(`"p":"dogs"`), 1)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	for name, token := range map[string]string{
+		"not base64":    "!!!",
+		"not JSON":      "bm90IGpzb24",
+		"unsigned":      unsigned(good),
+		"tampered with": tampered(permalinkToken(good)),
+		"signed elsewhere": func() string {
+			defer func(key []byte) { permalinkKey = key }(permalinkKey)
+			permalinkKey = []byte("another site")
+			return permalinkToken(good)
+		}(),
+		"not on Imgur":    bad(func(p *Page) { p.ImgurSource = "https://evil.example/a.png" }),
+		"not http":        bad(func(p *Page) { p.ImgurSource = "file://i.imgur.com/a.png" }),
+		"off the page":    bad(func(p *Page) { p.Top = 101 }),
+		"no such overlay": bad(func(p *Page) { p.Overlay = "../secret.png" }),
+		"no such layout": bad(func(p *Page) {
+			p.Layout = "spiral"
+			p.Panels = []Panel{{ImgurSource: good.ImgurSource}, {ImgurSource: good.ImgurSource}}
+		}),
+	} {
+		if _, err := pageFromToken(req, token); err == nil {
+			t.Errorf("pageFromToken of a token %s returned no error", name)
+		}
+	}
+}
+
+func TestPermalinkSizesBackground(t *testing.T) {
+	token := permalinkToken(Page{ImgurSource: "https://i.imgur.com/zHQ2rzI.png", Width: 610, Height: 679, Place: "cats", Overlay: defaultOverlay})
+	for _, tt := range []struct {
+		target, width, want string
+	}{
+		{"/", "", "/img/zHQ2rzI.png"},
+		{"/", "300", "/img/zHQ2rzIl.png"},
+		{"/", "200", "/img/zHQ2rzIm.png"},
+		{"/?size=huge", "200", "/img/zHQ2rzIh.png"},
+	} {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		if tt.width != "" {
+			req.Header.Set("Sec-CH-Viewport-Width", tt.width)
+		}
+		p, err := pageFromToken(req, token)
+		if err != nil {
+			t.Fatalf("pageFromToken returned error: %v", err)
+		}
+		if p.Background != tt.want {
+			t.Errorf("pageFromToken for %v wide at %v returned background %v, want %v", tt.width, tt.target, p.Background, tt.want)
+		}
+	}
+}
+
+// servePermalink runs a request for path through the router.
+func servePermalink(path string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	setupRouter().ServeHTTP(res, httptest.NewRequest("GET", "http://dickbutt.in"+path, nil))
+	return res
+}
+
+func TestPermalinkCacheControl(t *testing.T) {
+	srv := backgroundServer(t, 80, 60)
+	missing := imgurTestServer(t, http.NotFoundHandler())
+
+	good, _ := pagePermalink(Page{Place: "cats", ImgurSource: srv.URL + "/a.png", Top: 40, Bottom: 30, Overlay: defaultOverlay})
+	gone, _ := pagePermalink(Page{Place: "cats", ImgurSource: missing.URL + "/a.png", Top: 40, Bottom: 30, Overlay: defaultOverlay})
+
+	for _, tt := range []struct {
+		path   string
+		status int
+		cached bool
+	}{
+		{good + ".png", http.StatusOK, true},
+		{good + ".json", http.StatusOK, true},
+		{good + ".png?top=hello", http.StatusOK, false},
+		{good + ".png?filter=sepia", http.StatusOK, false},
+		{gone + ".png", http.StatusBadGateway, false},
+	} {
+		res := servePermalink(tt.path)
+		cached := res.Header().Get("Cache-Control") != ""
+		if res.Code != tt.status || cached != tt.cached {
+			t.Errorf("%v returned %v, cached %v, want %v, cached %v", tt.path, res.Code, cached, tt.status, tt.cached)
+		}
+	}
+}
+
+func TestOEmbed(t *testing.T) {
+	page, image := pagePermalink(Page{Place: "cats", ImgurSource: "https://i.imgur.com/zHQ2rzI.png", Width: 800, Height: 600, Top: 40, Bottom: 30, Author: "kJerAFK", Overlay: defaultOverlay})
+
+	res := servePermalink("/oembed?maxwidth=400&url=" + url.QueryEscape("http://dickbutt.in"+page))
+	var embed OEmbed
+	if err := json.Unmarshal(res.Body.Bytes(), &embed); err != nil {
+		t.Fatalf("Decoding oEmbed returned error: %v", err)
+	}
+	if embed.Type != "photo" || embed.URL != "http://dickbutt.in"+image+"?w=400" || embed.Width != 400 || embed.Height != 300 {
+		t.Errorf("oEmbed of %v returned %+v, want a 400x300 photo of its composite", page, embed)
+	}
+	if embed.AuthorName != "kJerAFK" || embed.AuthorURL != "http://imgur.com/user/kJerAFK" {
+		t.Errorf("oEmbed of %v credits %q at %q, want the background's poster", page, embed.AuthorName, embed.AuthorURL)
+	}
+
+	// A place would embed a different picture from the one shared.
+	for _, u := range []string{"http://dickbutt.in/cats", "http://example.com" + page, "http://dickbutt.in/p/nonsense"} {
+		if res := servePermalink("/oembed?url=" + url.QueryEscape(u)); res.Code != http.StatusNotFound {
+			t.Errorf("oEmbed of %v returned %v, want 404", u, res.Code)
+		}
+	}
+}
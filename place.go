@@ -0,0 +1,77 @@
+package main
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPlaceRunes bounds the length of a place; longer ones are cut short.
+const maxPlaceRunes = 64
+
+// maxMashupPlaces bounds how many places a mashup is made of; any more are
+// dropped.
+const maxMashupPlaces = 4
+
+// CanonicalPlace returns the one form of place that all its spellings
+// share: case folded, in Unicode NFC, with its words separated by single
+// hyphens, whether they were separated by whitespace, hyphens or
+// underscores, and at most maxPlaceRunes long.  It's empty if place has no
+// words at all.
+func CanonicalPlace(place string) string {
+	// A Caser keeps state, so can't be shared between requests.
+	place = norm.NFC.String(cases.Fold().String(place))
+	place = strings.Join(strings.FieldsFunc(place, isPlaceSeparator), "-")
+
+	if utf8.RuneCountInString(place) > maxPlaceRunes {
+		runes := []rune(place)
+		place = strings.TrimRight(string(runes[:maxPlaceRunes]), "-")
+	}
+	return place
+}
+
+// CanonicalMashup splits a requested place into the canonical places of a
+// mashup, which are separated by "+" or "/".  A plain place is a mashup of
+// one.
+func CanonicalMashup(requested string) []string {
+	var places []string
+	for _, part := range strings.FieldsFunc(requested, isMashupSeparator) {
+		if place := CanonicalPlace(part); place != "" && len(places) < maxMashupPlaces {
+			places = append(places, place)
+		}
+	}
+	return places
+}
+
+// mashupPlace returns the canonical name of the mashup of places.
+func mashupPlace(places []string) string {
+	return strings.Join(places, "+")
+}
+
+func isMashupSeparator(r rune) bool {
+	return r == '+' || r == '/'
+}
+
+func isPlaceSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r) || r == '-' || r == '_'
+}
+
+// placeQuery returns what to search Imgur for to find backgrounds for the
+// canonical place.
+func placeQuery(place string) string {
+	return strings.ReplaceAll(place, "-", " ")
+}
+
+// redirectToPlace permanently redirects req to the canonical place, keeping
+// the extension and query string it was asked for with.
+func redirectToPlace(res http.ResponseWriter, req *http.Request, place, ext string) {
+	target := "/" + placePath(place) + strings.ToLower(ext)
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	http.Redirect(res, req, target, http.StatusMovedPermanently)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxTrackedPlaces bounds how many places PlaceStats scores at once; past
+// it, the places whose scores have decayed the most are forgotten.
+const maxTrackedPlaces = 10000
+
+var placeStats = NewPlaceStats(6*time.Hour, 50)
+
+// PlaceStats keeps track of which places are being asked for.  Each request
+// adds one to a place's score, and scores halve every HalfLife, so trending
+// places are those asked for a lot lately.
+type PlaceStats struct {
+	HalfLife time.Duration
+
+	mu     sync.Mutex
+	scores map[string]*placeScore
+	recent []string // most recent last, without repeats
+	keep   int      // how many recent places to keep
+}
+
+type placeScore struct {
+	score   float64
+	updated time.Time
+}
+
+func NewPlaceStats(halfLife time.Duration, recent int) *PlaceStats {
+	return &PlaceStats{
+		HalfLife: halfLife,
+		scores:   make(map[string]*placeScore),
+		keep:     recent,
+	}
+}
+
+// decayed returns ps's score as of now.
+func (s *PlaceStats) decayed(ps *placeScore, now time.Time) float64 {
+	age := now.Sub(ps.updated)
+	return ps.score * math.Exp2(-float64(age)/float64(s.HalfLife))
+}
+
+// Record counts a request for place.
+func (s *PlaceStats) Record(place string) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ps, ok := s.scores[place]
+	if !ok {
+		if len(s.scores) >= maxTrackedPlaces {
+			s.prune(now)
+		}
+		ps = &placeScore{}
+		s.scores[place] = ps
+	}
+	ps.score = s.decayed(ps, now) + 1
+	ps.updated = now
+
+	for i, p := range s.recent {
+		if p == place {
+			s.recent = append(s.recent[:i], s.recent[i+1:]...)
+			break
+		}
+	}
+	s.recent = append(s.recent, place)
+	if len(s.recent) > s.keep {
+		s.recent = s.recent[len(s.recent)-s.keep:]
+	}
+}
+
+// prune forgets the lower scoring half of the places.  The caller must
+// hold mu.
+func (s *PlaceStats) prune(now time.Time) {
+	ranked := s.ranked(now)
+	for _, r := range ranked[len(ranked)/2:] {
+		delete(s.scores, r.place)
+	}
+}
+
+type rankedPlace struct {
+	place string
+	score float64
+}
+
+// ranked returns every place, highest score first.  The caller must hold mu.
+func (s *PlaceStats) ranked(now time.Time) []rankedPlace {
+	ranked := make([]rankedPlace, 0, len(s.scores))
+	for place, ps := range s.scores {
+		ranked = append(ranked, rankedPlace{place, s.decayed(ps, now)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].place < ranked[j].place
+	})
+	return ranked
+}
+
+// Trending returns up to n places with the highest scores.
+func (s *PlaceStats) Trending(n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ranked := s.ranked(time.Now())
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	places := make([]string, len(ranked))
+	for i, r := range ranked {
+		places[i] = r.place
+	}
+	return places
+}
+
+// Recent returns up to n of the places most recently asked for, newest
+// first.
+func (s *PlaceStats) Recent(n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	places := []string{}
+	for i := len(s.recent) - 1; i >= 0 && len(places) < n; i-- {
+		places = append(places, s.recent[i])
+	}
+	return places
+}
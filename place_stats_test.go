@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlaceStats(t *testing.T) {
+	s := NewPlaceStats(time.Hour, 3)
+	for _, place := range []string{"cats", "dogs", "cats", "birds", "fish", "cats", "dogs"} {
+		s.Record(place)
+	}
+
+	if got, want := s.Trending(2), []string{"cats", "dogs"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Trending returned %v, want %v", got, want)
+	}
+	// Repeats move to the front rather than taking up room twice.
+	if got, want := s.Recent(10), []string{"dogs", "cats", "fish"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Recent returned %v, want %v", got, want)
+	}
+}
+
+func TestPlaceStatsDecay(t *testing.T) {
+	s := NewPlaceStats(time.Hour, 10)
+	s.Record("cats")
+	s.Record("cats")
+	s.Record("dogs")
+
+	// Two requests two half lives ago count for less than one now.
+	s.scores["cats"].updated = s.scores["cats"].updated.Add(-2 * time.Hour)
+	if got := s.Trending(1); len(got) != 1 || got[0] != "dogs" {
+		t.Errorf("Trending returned %v, want dogs", got)
+	}
+}
+
+func TestHomeHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://dickbutt.in/", nil)
+	req.Header.Set("Accept", "text/html")
+	res := httptest.NewRecorder()
+	HomeHandler(res, req)
+	if !strings.Contains(res.Body.String(), "<form") {
+		t.Errorf("HomeHandler returned %v %s, want the home page", res.Code, res.Body)
+	}
+
+	req.Header.Set("Accept", "*/*")
+	res = httptest.NewRecorder()
+	HomeHandler(res, req)
+	if !strings.HasPrefix(res.Body.String(), "Please goto http://dickbutt.in/") {
+		t.Errorf("HomeHandler returned %s, want the instructions", res.Body)
+	}
+}
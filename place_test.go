@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalPlace(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"funny-cats", "funny-cats"},
+		{"Funny Cats", "funny-cats"},
+		{"  funny__cats--  ", "funny-cats"},
+		{"funny\tcats\n", "funny-cats"},
+		{"STRASSE", "strasse"},
+		{"Straße", "strasse"},
+		{"café", "café"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφοσ"},
+		{"-_ \t", ""},
+		{"", ""},
+		{strings.Repeat("a", 70), strings.Repeat("a", maxPlaceRunes)},
+		{strings.Repeat("a", maxPlaceRunes-1) + " b", strings.Repeat("a", maxPlaceRunes-1)},
+	} {
+		if got := CanonicalPlace(tt.in); got != tt.want {
+			t.Errorf("CanonicalPlace(%q) returned %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalMashup(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []string
+	}{
+		{"Funny Cats", []string{"funny-cats"}},
+		{"cats+dogs", []string{"cats", "dogs"}},
+		{"cats/Dogs/", []string{"cats", "dogs"}},
+		{"cats++ +dogs", []string{"cats", "dogs"}},
+		{"a+b+c+d+e+f", []string{"a", "b", "c", "d"}},
+		{"+/", nil},
+		{"", nil},
+	} {
+		if got := CanonicalMashup(tt.in); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("CanonicalMashup(%q) returned %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
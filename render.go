@@ -0,0 +1,218 @@
+package main
+
+import (
+	"golang.org/x/image/draw"
+
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// PageData is the JSON form of a Page.
+type PageData struct {
+	Place      string `json:"place"`
+	Image      string `json:"image"`      // The direct link to the background on Imgur
+	Background string `json:"background"` // The background through the image proxy
+	Overlay    struct {
+		Image string `json:"image"`
+		Top   int    `json:"top"`  // Percent of the background's height
+		Left  int    `json:"left"` // Percent of the background's width
+	} `json:"overlay"`
+	Title     string       `json:"title,omitempty"`
+	Author    string       `json:"author,omitempty"`
+	Permalink string       `json:"permalink,omitempty"`
+	Points    int          `json:"points"`
+	Caption   *CaptionData `json:"caption,omitempty"`
+	Meme      *MemeData    `json:"meme,omitempty"`
+	Filter    string       `json:"filter,omitempty"`
+	Layout    string       `json:"layout,omitempty"`
+	Panels    []PanelData  `json:"panels,omitempty"` // A mashup's backgrounds, in place of the one above
+}
+
+// PanelData is the JSON form of a mashup's Panel.
+type PanelData struct {
+	Place      string `json:"place"`
+	Image      string `json:"image"`
+	Background string `json:"background"`
+	Title      string `json:"title,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Permalink  string `json:"permalink,omitempty"`
+	Points     int    `json:"points"`
+}
+
+type CaptionData struct {
+	Text   string `json:"text"`
+	Author string `json:"author,omitempty"`
+}
+
+type MemeData struct {
+	Top    string `json:"top,omitempty"`
+	Bottom string `json:"bottom,omitempty"`
+}
+
+// render writes out p as format, after applying the page modes asked for in
+// req's query string:
+//
+//	?comment	caption the page with the item's top comment
+//	?top=, ?bottom=	meme text along the top and bottom; the top's is the
+//			place's name if there's none
+//	?filter=	filters applied to the composite; see parseFilters
+func render(res http.ResponseWriter, req *http.Request, p Page, format string) {
+	if _, ok := req.URL.Query()["comment"]; ok && p.ItemID != "" {
+		p.Caption, p.CaptionAuthor = TopComment(p.ItemID)
+	}
+	if top, bottom, ok := requestMeme(req, p.Place); ok {
+		p.TopText, p.BottomText = top, bottom
+	}
+	if filter := req.URL.Query().Get("filter"); filter != "" {
+		p.Filter = parseFilters(filter)
+	}
+
+	switch format {
+	case "image/png", "image/jpeg":
+		writeComposite(res, req, p, format)
+	case "image/gif":
+		writeAnimation(res, req, p)
+	case "application/json":
+		writeJSON(res, req, p)
+	case "text/plain":
+		writeText(res, req, p)
+	default:
+		writePage(res, req, p)
+	}
+}
+
+// writeComposite writes out p rendered server-side as a PNG or JPEG, scaled
+// down to ?w= pixels wide if that's asked for.
+func writeComposite(res http.ResponseWriter, req *http.Request, p Page, format string) {
+	composite, err := Composite(p)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	var img image.Image = composite
+	b := img.Bounds()
+	if w, err := strconv.Atoi(req.URL.Query().Get("w")); err == nil && w > 0 && w < b.Dx() {
+		scaled := image.NewRGBA(image.Rect(0, 0, w, max(1, b.Dy()*w/b.Dx())))
+		draw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, b, draw.Src, nil)
+		img = scaled
+	}
+
+	res.Header().Set("Content-Type", format)
+	if format == "image/jpeg" {
+		err = jpeg.Encode(res, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(res, img)
+	}
+	if err != nil {
+		log.Printf("render: %v", err)
+	}
+}
+
+// writeJSON writes out p as PageData.
+func writeJSON(res http.ResponseWriter, req *http.Request, p Page) {
+	data := PageData{
+		Place:      p.Place,
+		Image:      p.ImgurSource,
+		Background: absURL(req, p.Background),
+		Title:      p.Title,
+		Author:     p.Author,
+		Permalink:  p.Permalink,
+		Points:     p.Points,
+	}
+	if overlay, err := assets.URL(p.Overlay); err == nil {
+		data.Overlay.Image = absURL(req, overlay)
+	}
+	data.Overlay.Top = p.Top
+	data.Overlay.Left = p.Bottom
+	if p.Caption != "" {
+		data.Caption = &CaptionData{Text: p.Caption, Author: p.CaptionAuthor}
+	}
+	if p.TopText != "" || p.BottomText != "" {
+		data.Meme = &MemeData{Top: p.TopText, Bottom: p.BottomText}
+	}
+	data.Filter = p.Filter
+	data.Layout = p.Layout
+	for _, panel := range p.Panels {
+		data.Panels = append(data.Panels, PanelData{
+			Place:      panel.Place,
+			Image:      panel.ImgurSource,
+			Background: absURL(req, panel.Background),
+			Title:      panel.Title,
+			Author:     panel.Author,
+			Permalink:  panel.Permalink,
+			Points:     panel.Points,
+		})
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(res).Encode(data); err != nil {
+		log.Printf("render: %v", err)
+	}
+}
+
+// writeText writes out p for a terminal: the composite drawn as ANSI
+// truecolor art, or plain ASCII art with ?mono, ?cols= characters wide,
+// followed by its credits and permalink.
+func writeText(res http.ResponseWriter, req *http.Request, p Page) {
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if img, err := Composite(p); err == nil {
+		_, mono := req.URL.Query()["mono"]
+		writeArt(res, img, artCols(req), mono)
+	} else if len(p.Panels) > 0 {
+		for _, panel := range p.Panels {
+			fmt.Fprintln(res, panel.ImgurSource)
+		}
+	} else {
+		fmt.Fprintln(res, p.ImgurSource)
+	}
+
+	if p.Caption != "" {
+		fmt.Fprintf(res, "%q - %s\n", p.Caption, p.CaptionAuthor)
+	}
+	if p.Permalink != "" {
+		fmt.Fprintln(res, attribution(p.Title, p.Author, p.Permalink, p.Points))
+	}
+	for _, panel := range p.Panels {
+		if panel.Permalink != "" {
+			fmt.Fprintf(res, "%s: %s\n", panel.Place, attribution(panel.Title, panel.Author, panel.Permalink, panel.Points))
+		}
+	}
+	page, _ := pagePermalink(p)
+	fmt.Fprintln(res, absURL(req, page))
+}
+
+// attribution credits a background in a line of text.
+func attribution(title, author, permalink string, points int) string {
+	if title == "" {
+		title = "Untitled"
+	}
+	by := ""
+	if author != "" {
+		by = " by " + author
+	}
+	return fmt.Sprintf("%q%s on Imgur, %d points: %s", title, by, points, permalink)
+}
+
+// absURL resolves a path on this site against the host req was made to.
+// Behind Heroku's router the original scheme is in X-Forwarded-Proto.
+func absURL(req *http.Request, path string) string {
+	if path == "" || path[0] != '/' {
+		return path
+	}
+
+	scheme := req.Header.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + req.Host + path
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sitemapPlaces is how many of the trending places the sitemap lists.
+const sitemapPlaces = 500
+
+// defaultSecurityContact is where security.txt sends reports when
+// SECURITY_CONTACT isn't set.
+const defaultSecurityContact = "https://github.com/unclelarrysvan/dickbutt/issues"
+
+// reservedPlace matches the names browsers, crawlers and scanners ask every
+// site for, which would otherwise each be searched for on Imgur as a place.
+// Names starting with a dot (.env, .git, .well-known) are reserved too.
+var reservedPlace = regexp.MustCompile(`(?i)^(\..*|(favicon|apple-touch-icon|android-chrome|mstile)[-\w]*\.(ico|png|svg)|robots\.txt|sitemap\.xml|site\.webmanifest|manifest\.json|browserconfig\.xml|ads\.txt|humans\.txt|security\.txt)$`)
+
+// iconAliases are the icons served from the site root, where browsers look
+// for them without being told, and the assets they are.
+var iconAliases = map[string]string{
+	"/favicon.ico":                      "favicon.ico",
+	"/apple-touch-icon.png":             "apple-touch-icon.png",
+	"/apple-touch-icon-precomposed.png": "apple-touch-icon.png",
+}
+
+// wellKnown are the documents served under /.well-known/.
+var wellKnown = map[string]http.HandlerFunc{
+	"security.txt": SecurityTxtHandler,
+}
+
+// setupReservedRoutes adds the routes for the reserved names to r.  They
+// must come before the {place} catch-all.
+func setupReservedRoutes(r *mux.Router) {
+	for path, name := range iconAliases {
+		r.HandleFunc(path, iconHandler(name))
+	}
+	r.HandleFunc("/robots.txt", RobotsHandler)
+	r.HandleFunc("/sitemap.xml", SitemapHandler)
+	r.HandleFunc("/site.webmanifest", ManifestHandler)
+	r.HandleFunc("/.well-known/{name}", WellKnownHandler)
+}
+
+// isReservedPlace reports whether place is one of the reserved names, and
+// so not a place at all.
+func isReservedPlace(place string) bool {
+	return reservedPlace.MatchString(place)
+}
+
+// iconHandler serves the asset name at a path that can't be fingerprinted.
+// It's cached for a day, as browsers rarely check for a new icon anyway.
+func iconHandler(name string) http.HandlerFunc {
+	asset, ok := assets.byName[name]
+	if !ok {
+		panic("no icon asset " + name)
+	}
+	return func(res http.ResponseWriter, req *http.Request) {
+		serveAsset(res, req, asset, "public, max-age=86400")
+	}
+}
+
+// RobotsHandler lets crawlers see places but keeps them out of the routes
+// that cost Imgur credits without making a page, and points them at the
+// sitemap.
+func RobotsHandler(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	res.Header().Set("Cache-Control", "public, max-age=86400")
+	fmt.Fprintln(res, "User-agent: *")
+	fmt.Fprintln(res, "Disallow: /search")
+	fmt.Fprintln(res, "Disallow: /share")
+	fmt.Fprintln(res, "Disallow: /img/")
+	fmt.Fprintln(res, "Disallow: /random")
+	fmt.Fprintln(res)
+	fmt.Fprintln(res, "Sitemap: "+absURL(req, "/sitemap.xml"))
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+}
+
+// SitemapHandler lists the home page and the places trending now.  Places
+// get a different background every time, so they change always.
+func SitemapHandler(res http.ResponseWriter, req *http.Request) {
+	set := sitemapURLSet{URLs: []sitemapURL{{Loc: absURL(req, "/"), ChangeFreq: "hourly"}}}
+	for _, place := range placeStats.Trending(sitemapPlaces) {
+		set.URLs = append(set.URLs, sitemapURL{Loc: absURL(req, "/"+placePath(place)), ChangeFreq: "always"})
+	}
+
+	res.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	res.Header().Set("Cache-Control", "public, max-age=3600")
+	fmt.Fprint(res, xml.Header)
+	enc := xml.NewEncoder(res)
+	enc.Indent("", "\t")
+	if err := enc.Encode(set); err != nil {
+		log.Printf("sitemap: %v", err)
+	}
+}
+
+type manifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// ManifestHandler describes the site to browsers that can pin it to a home
+// screen.
+func ManifestHandler(res http.ResponseWriter, req *http.Request) {
+	var icons []manifestIcon
+	for _, icon := range []struct{ name, sizes string }{
+		{"icon-192.png", "192x192"},
+		{"apple-touch-icon.png", "180x180"},
+	} {
+		src, err := assets.URL(icon.name)
+		if err != nil {
+			panic(err)
+		}
+		icons = append(icons, manifestIcon{Src: src, Sizes: icon.sizes, Type: "image/png"})
+	}
+
+	manifest := map[string]interface{}{
+		"name":             "dickbutt.in",
+		"short_name":       "dickbutt",
+		"start_url":        "/",
+		"display":          "browser",
+		"background_color": "#ffffff",
+		"icons":            icons,
+	}
+
+	res.Header().Set("Content-Type", "application/manifest+json")
+	res.Header().Set("Cache-Control", "public, max-age=86400")
+	if err := json.NewEncoder(res).Encode(manifest); err != nil {
+		log.Printf("manifest: %v", err)
+	}
+}
+
+// WellKnownHandler serves the documents in wellKnown, and nothing else.
+func WellKnownHandler(res http.ResponseWriter, req *http.Request) {
+	handler, ok := wellKnown[mux.Vars(req)["name"]]
+	if !ok {
+		http.NotFound(res, req)
+		return
+	}
+	handler(res, req)
+}
+
+// SecurityTxtHandler says where to report security problems, as in RFC
+// 9116.  The contact comes from SECURITY_CONTACT.
+func SecurityTxtHandler(res http.ResponseWriter, req *http.Request) {
+	contact := os.Getenv("SECURITY_CONTACT")
+	if contact == "" {
+		contact = defaultSecurityContact
+	}
+	if strings.Contains(contact, "@") && !strings.Contains(contact, ":") {
+		contact = "mailto:" + contact
+	}
+	expires := time.Now().UTC().AddDate(1, 0, 0).Truncate(24 * time.Hour)
+
+	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(res, "Contact: "+contact)
+	fmt.Fprintln(res, "Expires: "+expires.Format(time.RFC3339))
+	fmt.Fprintln(res, "Canonical: "+absURL(req, "/.well-known/security.txt"))
+}
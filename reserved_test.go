@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsReservedPlace(t *testing.T) {
+	for _, tt := range []struct {
+		place string
+		want  bool
+	}{
+		{"favicon.ico", true},
+		{"FAVICON.ICO", true},
+		{"apple-touch-icon-120x120-precomposed.png", true},
+		{"android-chrome-192x192.png", true},
+		{"robots.txt", true},
+		{"sitemap.xml", true},
+		{".env", true},
+		{".git", true},
+		{".well-known", true},
+		{"security.txt", true},
+		{"funny-cats", false},
+		{"favicon", false},
+		{"robots", false},
+		{"my.robots.txt", false},
+		{"cats.png", false},
+	} {
+		if got := isReservedPlace(tt.place); got != tt.want {
+			t.Errorf("isReservedPlace(%q) returned %v, want %v", tt.place, got, tt.want)
+		}
+	}
+}
+
+func TestReservedRoutes(t *testing.T) {
+	for _, tt := range []struct {
+		path   string
+		status int
+	}{
+		{"/favicon.ico", http.StatusOK},
+		{"/robots.txt", http.StatusOK},
+		{"/site.webmanifest", http.StatusOK},
+		{"/.well-known/security.txt", http.StatusOK},
+		{"/.well-known/nothing", http.StatusNotFound},
+		{"/.env", http.StatusNotFound},
+		{"/apple-touch-icon-76x76.png", http.StatusNotFound},
+	} {
+		res := httptest.NewRecorder()
+		setupRouter().ServeHTTP(res, httptest.NewRequest("GET", tt.path, nil))
+		if res.Code != tt.status {
+			t.Errorf("%v returned %v, want %v", tt.path, res.Code, tt.status)
+		}
+	}
+}
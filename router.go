@@ -2,13 +2,44 @@ package main
 
 import (
 	"github.com/gorilla/mux"
+
 	"net/http"
+	"strings"
 )
 
+// reservedPrefixes are the first segments of the routes with paths of their
+// own below them, which a place of several segments mustn't shadow.
+var reservedPrefixes = map[string]bool{
+	"img": true, "t": true, "r": true, "p": true, "assets": true,
+	"integrations": true, ".well-known": true,
+}
+
 func setupRouter() *mux.Router {
 	r := mux.NewRouter()
+	setupSubdomainRoutes(r)
 	r.HandleFunc("/", HomeHandler)
-	r.HandleFunc("/{place}", DickButtHandler)
-	r.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir("./assets/"))))
+	// These must come before the {place} catch-all.
+	setupReservedRoutes(r)
+	r.HandleFunc("/search", SearchHandler)
+	r.HandleFunc("/share", ShareHandler).Methods("POST")
+	r.HandleFunc("/img/{id}", ImageProxyHandler)
+	r.HandleFunc(permalinkPath+"{token}", PermalinkHandler)
+	r.HandleFunc("/oembed", OEmbedHandler)
+	r.HandleFunc("/integrations/slack", SlackHandler).Methods("POST")
+	r.HandleFunc("/integrations/discord", DiscordHandler).Methods("POST")
+	r.HandleFunc("/t/{tag}", TagHandler)
+	r.HandleFunc("/r/{subreddit}", SubredditHandler)
+	r.HandleFunc("/memes", MemesHandler)
+	r.HandleFunc("/hot", HotHandler)
+	r.HandleFunc("/random", RandomHandler)
+	r.HandleFunc(assetsPath+"{name:.+}", AssetHandler)
+	r.HandleFunc("/{place:.+}", DickButtHandler).MatcherFunc(isPlacePath)
 	return r
 }
+
+// isPlacePath reports whether req's path is a place, rather than under one
+// of reservedPrefixes.
+func isPlacePath(req *http.Request, match *mux.RouteMatch) bool {
+	first, _, several := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	return !several || !reservedPrefixes[first]
+}
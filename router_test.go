@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPlacePath(t *testing.T) {
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{
+		{"/funny-cats", true},
+		{"/cats/dogs", true},
+		{"/img", true},
+		{"/t", true},
+		{"/img/a/b", false},
+		{"/t/a/b", false},
+		{"/r/a/b", false},
+		{"/p/x/y", false},
+		{"/assets/x/y", false},
+		{"/.well-known/a/b", false},
+	} {
+		if got := isPlacePath(httptest.NewRequest("GET", tt.path, nil), nil); got != tt.want {
+			t.Errorf("isPlacePath(%v) returned %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
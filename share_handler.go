@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bitbucket.org/liamstask/go-imgur/imgur"
+
+	"bytes"
+	"image/png"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shareClient uploads shares.  It's deliberately never authorized: anyone
+// can put any caption on a share, so shares are anonymous uploads rather
+// than posts on the team account client may be authorized with.
+var shareClient = imgur.NewClient(nil, clientID, clientSecret)
+
+// Each address can share at most maxShares images every shareWindow.
+const (
+	maxShares   = 10
+	shareWindow = time.Hour
+)
+
+// shares counts the shares from each address since the window started.
+var shares = struct {
+	sync.Mutex
+	start time.Time
+	count map[string]int
+}{count: make(map[string]int)}
+
+// ShareHandler uploads the composite of the page the visitor is looking at
+// to Imgur and sends them to the new image.  Only the site's own pages can
+// share, and only so often.
+func ShareHandler(res http.ResponseWriter, req *http.Request) {
+	if !sameOrigin(req) {
+		http.Error(res, "shares must come from this site", http.StatusForbidden)
+		return
+	}
+	if !allowShare(clientAddr(req), time.Now()) {
+		http.Error(res, "too many shares, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	top, _ := strconv.Atoi(req.FormValue("top"))
+	bottom, _ := strconv.Atoi(req.FormValue("bottom"))
+	p := Page{
+		ImgurSource:   req.FormValue("src"),
+		Top:           top,
+		Bottom:        bottom,
+		Place:         req.FormValue("place"),
+		Caption:       req.FormValue("caption"),
+		CaptionAuthor: req.FormValue("caption_author"),
+		TopText:       memeText(req.FormValue("top_text")),
+		BottomText:    memeText(req.FormValue("bottom_text")),
+		Filter:        parseFilters(req.FormValue("filter")),
+		Overlay:       defaultOverlay,
+	}
+	if overlay := req.FormValue("overlay"); isOverlay(overlay) {
+		p.Overlay = overlay
+	}
+	if layout, srcs := req.FormValue("layout"), req.Form["panel"]; mashupLayouts[layout] && len(srcs) > 1 && len(srcs) <= maxMashupPlaces {
+		p.Layout = layout
+		p.Panels = layoutPanels(layout, len(srcs))
+		for i, src := range srcs {
+			p.Panels[i].ImgurSource = src
+		}
+	}
+
+	img, err := Composite(p)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page, _ := pagePermalink(p)
+	uploaded, err := shareClient.Image.Upload(buf, p.Place+".png", &imgur.UploadOptions{
+		Title:       p.Place + " dickbutt",
+		Description: "Made at " + absURL(req, page),
+	})
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	http.Redirect(res, req, uploaded.Link, http.StatusSeeOther)
+}
+
+// sameOrigin reports whether req was posted from a page on its own host,
+// going by the Origin header or, in browsers that don't send one, Referer.
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		origin = req.Header.Get("Referer")
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, req.Host)
+}
+
+// clientAddr returns the address req came from.  Heroku's router appends
+// it to X-Forwarded-For, so it's the last one there.
+func clientAddr(req *http.Request) string {
+	if fwd := req.Header.Get("X-Forwarded-For"); fwd != "" {
+		addrs := strings.Split(fwd, ",")
+		return strings.TrimSpace(addrs[len(addrs)-1])
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// allowShare counts a share from addr at now, reporting whether it's
+// within maxShares.  The counts start again every shareWindow.
+func allowShare(addr string, now time.Time) bool {
+	shares.Lock()
+	defer shares.Unlock()
+
+	if now.Sub(shares.start) >= shareWindow {
+		shares.start = now
+		shares.count = make(map[string]int)
+	}
+	if shares.count[addr] >= maxShares {
+		return false
+	}
+	shares.count[addr]++
+	return true
+}
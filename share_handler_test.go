@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bitbucket.org/liamstask/go-imgur/imgur"
+
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareHandlerOrigin(t *testing.T) {
+	for _, tt := range []struct {
+		header, value string
+		want          int
+	}{
+		{"", "", http.StatusForbidden},
+		{"Origin", "https://evil.example", http.StatusForbidden},
+		{"Referer", "https://evil.example/funny-cats", http.StatusForbidden},
+		// From the site itself the share gets as far as rendering, which
+		// fails without a background.
+		{"Origin", "https://dickbutt.in", http.StatusBadRequest},
+		{"Referer", "https://dickbutt.in/funny-cats", http.StatusBadRequest},
+	} {
+		form := url.Values{"src": {"https://evil.example/x.png"}, "place": {"funny-cats"}}
+		req := httptest.NewRequest("POST", "https://dickbutt.in/share", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		res := httptest.NewRecorder()
+		ShareHandler(res, req)
+		if res.Code != tt.want {
+			t.Errorf("Sharing with %s %q returned %v, want %v", tt.header, tt.value, res.Code, tt.want)
+		}
+	}
+}
+
+func TestShareHandlerUpload(t *testing.T) {
+	bg := backgroundServer(t, 64, 48)
+
+	var description string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		description = r.FormValue("description")
+		fmt.Fprint(w, `{"data":{"id":"abc","link":"https://i.imgur.com/abc.png"},"success":true,"status":200}`)
+	}))
+	defer api.Close()
+
+	oldClient := shareClient
+	defer func() { shareClient = oldClient }()
+	shareClient = imgur.NewClient(nil, "clientID", "clientSecret")
+	shareClient.BaseURL, _ = url.Parse(api.URL + "/3/")
+
+	form := url.Values{"src": {bg.URL + "/a.png"}, "place": {"funny cats?"}, "top": {"40"}, "bottom": {"30"}}
+	req := httptest.NewRequest("POST", "http://dickbutt.in/share", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://dickbutt.in")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	res := httptest.NewRecorder()
+	ShareHandler(res, req)
+
+	if res.Code != http.StatusSeeOther || res.Header().Get("Location") != "https://i.imgur.com/abc.png" {
+		t.Fatalf("Sharing returned %v to %q, want %v to the upload", res.Code, res.Header().Get("Location"), http.StatusSeeOther)
+	}
+
+	// The description links to the exact page that was shared.
+	const prefix = "Made at https://dickbutt.in" + permalinkPath
+	if !strings.HasPrefix(description, prefix) {
+		t.Fatalf("Upload description is %q, want it to start with %q", description, prefix)
+	}
+	p, err := pageFromToken(req, strings.TrimPrefix(description, prefix))
+	if err != nil || p.Place != "funny cats?" || p.ImgurSource != bg.URL+"/a.png" {
+		t.Errorf("Upload description links to %+v, %v, want the shared page", p, err)
+	}
+}
+
+func TestAllowShare(t *testing.T) {
+	now := time.Now().Add(2 * shareWindow)
+	for i := 0; i < maxShares; i++ {
+		if !allowShare("198.51.100.1", now) {
+			t.Fatalf("allowShare refused share %v, want %v allowed", i+1, maxShares)
+		}
+	}
+	if allowShare("198.51.100.1", now) {
+		t.Errorf("allowShare allowed share %v, want it refused", maxShares+1)
+	}
+	if !allowShare("198.51.100.2", now) {
+		t.Errorf("allowShare refused another address")
+	}
+	if !allowShare("198.51.100.1", now.Add(shareWindow)) {
+		t.Errorf("allowShare refused a share in the next window")
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	req := httptest.NewRequest("POST", "/share", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if got := clientAddr(req); got != "10.0.0.1" {
+		t.Errorf("clientAddr returned %q, want 10.0.0.1", got)
+	}
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+	if got := clientAddr(req); got != "198.51.100.7" {
+		t.Errorf("clientAddr returned %q, want 198.51.100.7", got)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// slackMaxSkew is how old a request's timestamp can be before it's
+	// taken for a replay.
+	slackMaxSkew = 5 * time.Minute
+
+	// slackResponseHost is the only host deferred responses are posted to.
+	slackResponseHost = "hooks.slack.com"
+)
+
+// slackDeadline is how long a slash command waits for its page before
+// answering that it's still looking; Slack gives up after 3 seconds.
+var slackDeadline = 2500 * time.Millisecond
+
+// slackSigningSecret verifies that requests come from Slack, from
+// SLACK_SIGNING_SECRET; the integration is off without it.
+var slackSigningSecret = os.Getenv("SLACK_SIGNING_SECRET")
+
+var slackClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	errSlackSignature = errors.New("bad Slack signature")
+	errSlackStale     = errors.New("stale Slack request")
+)
+
+// SlackMessage is a message sent back to Slack, made of Block Kit blocks.
+type SlackMessage struct {
+	ResponseType    string       `json:"response_type,omitempty"` // "in_channel" or "ephemeral"
+	ReplaceOriginal bool         `json:"replace_original,omitempty"`
+	Text            string       `json:"text"` // Shown in notifications
+	Blocks          []SlackBlock `json:"blocks,omitempty"`
+}
+
+type SlackBlock struct {
+	Type     string        `json:"type"`
+	ImageURL string        `json:"image_url,omitempty"`
+	AltText  string        `json:"alt_text,omitempty"`
+	Title    *SlackText    `json:"title,omitempty"`
+	Elements []interface{} `json:"elements,omitempty"` // SlackTexts or SlackButtons
+}
+
+type SlackText struct {
+	Type string `json:"type"` // "plain_text" or "mrkdwn"
+	Text string `json:"text"`
+}
+
+type SlackButton struct {
+	Type     string    `json:"type"`
+	Text     SlackText `json:"text"`
+	ActionID string    `json:"action_id"`
+	Value    string    `json:"value"`
+	Style    string    `json:"style,omitempty"`
+}
+
+// slackPayload is the part of an interactive callback we use.
+type slackPayload struct {
+	Type        string `json:"type"`
+	ResponseURL string `json:"response_url"`
+	User        struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// SlackHandler answers both the /dickbutt slash command and the callbacks
+// from the buttons on its messages, which Slack posts as a payload.
+func SlackHandler(res http.ResponseWriter, req *http.Request) {
+	if slackSigningSecret == "" {
+		http.NotFound(res, req)
+		return
+	}
+	form, err := verifySlackRequest(req, time.Now())
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if payload := form.Get("payload"); payload != "" {
+		slackCallback(res, req, payload)
+		return
+	}
+	slackCommand(res, req, form)
+}
+
+// verifySlackRequest checks req's signature, an HMAC of its timestamp and
+// body keyed with the signing secret, and returns the form it posted.
+func verifySlackRequest(req *http.Request, now time.Time) (url.Values, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	ts := req.Header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, errSlackSignature
+	}
+	if skew := now.Sub(time.Unix(sec, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return nil, errSlackStale
+	}
+
+	mac := hmac.New(sha256.New, []byte(slackSigningSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(req.Header.Get("X-Slack-Signature"))) {
+		return nil, errSlackSignature
+	}
+
+	return url.ParseQuery(string(body))
+}
+
+// slackCommand answers "/dickbutt cats" with the cats page, in the channel.
+// If Imgur takes too long, Slack is told we're looking and the page is
+// posted to the command's response_url when it's ready.
+func slackCommand(res http.ResponseWriter, req *http.Request, form url.Values) {
+	places := CanonicalMashup(form.Get("text"))
+	if len(places) == 0 {
+		writeSlack(res, SlackMessage{
+			ResponseType: "ephemeral",
+			Text:         "Usage: " + form.Get("command") + " <place>, or several places joined with +",
+		})
+		return
+	}
+	placeStats.Record(mashupPlace(places))
+
+	done := make(chan SlackMessage, 1)
+	go func() {
+		done <- slackPageMessage(req, newPlacePage(req, places), true)
+	}()
+
+	select {
+	case msg := <-done:
+		writeSlack(res, msg)
+	case <-time.After(slackDeadline):
+		responseURL := form.Get("response_url")
+		go func() {
+			postSlack(responseURL, <-done)
+		}()
+		writeSlack(res, SlackMessage{
+			ResponseType: "ephemeral",
+			Text:         "Looking for " + mashupPlace(places) + " on Imgur…",
+		})
+	}
+}
+
+// slackCallback handles a button press.  Slack only wants an
+// acknowledgement back; the new message goes to the response_url.
+//
+//	shuffle	replaces the message with a new page for the same place
+//	post	replaces it with the same page, without the buttons
+func slackCallback(res http.ResponseWriter, req *http.Request, payload string) {
+	var p slackPayload
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Type != "block_actions" || len(p.Actions) == 0 {
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+
+	action := p.Actions[0]
+	switch action.ActionID {
+	case "shuffle":
+		places := CanonicalMashup(action.Value)
+		if len(places) == 0 {
+			break
+		}
+		go func() {
+			msg := slackPageMessage(req, newPlacePage(req, places), true)
+			msg.ReplaceOriginal = true
+			postSlack(p.ResponseURL, msg)
+		}()
+	case "post":
+		page, err := pageFromToken(req, action.Value)
+		if err != nil {
+			break
+		}
+		msg := slackPageMessage(req, page, false)
+		msg.ReplaceOriginal = true
+		if p.User.ID != "" {
+			msg.Blocks = append(msg.Blocks, SlackBlock{
+				Type:     "context",
+				Elements: []interface{}{SlackText{"mrkdwn", "Posted by <@" + p.User.ID + ">"}},
+			})
+		}
+		go postSlack(p.ResponseURL, msg)
+	}
+	res.WriteHeader(http.StatusOK)
+}
+
+// slackPageMessage shows p's composite in the channel, with its credits
+// and, if buttons, the buttons to shuffle and post it.
+func slackPageMessage(req *http.Request, p Page, buttons bool) SlackMessage {
+	_, image := pagePermalink(p)
+	title := p.Place + " dickbutt"
+
+	msg := SlackMessage{
+		ResponseType: "in_channel",
+		Text:         title,
+		Blocks: []SlackBlock{{
+			Type:     "image",
+			ImageURL: absURL(req, image),
+			AltText:  title,
+			Title:    &SlackText{"plain_text", title},
+		}},
+	}
+
+	var credits []interface{}
+	if p.Permalink != "" {
+		credits = append(credits, SlackText{"mrkdwn", slackCredit(p.Title, p.Author, p.Permalink, p.Points)})
+	}
+	for _, panel := range p.Panels {
+		if panel.Permalink != "" {
+			credits = append(credits, SlackText{"mrkdwn", slackEscape(panel.Place) + ": " + slackCredit(panel.Title, panel.Author, panel.Permalink, panel.Points)})
+		}
+	}
+	if len(credits) > 0 {
+		msg.Blocks = append(msg.Blocks, SlackBlock{Type: "context", Elements: credits})
+	}
+
+	if buttons {
+		msg.Blocks = append(msg.Blocks, SlackBlock{
+			Type: "actions",
+			Elements: []interface{}{
+				SlackButton{Type: "button", Text: SlackText{"plain_text", "Shuffle"}, ActionID: "shuffle", Value: p.Place},
+				SlackButton{Type: "button", Text: SlackText{"plain_text", "Post"}, ActionID: "post", Value: permalinkToken(p), Style: "primary"},
+			},
+		})
+	}
+	return msg
+}
+
+// slackCredit credits a background in Slack's markup.
+func slackCredit(title, author, permalink string, points int) string {
+	if title == "" {
+		title = "Untitled"
+	}
+	credit := "<" + permalink + "|" + slackEscape(title) + ">"
+	if author != "" {
+		credit += " by " + slackEscape(author)
+	}
+	return credit + " on Imgur, " + strconv.Itoa(points) + " points"
+}
+
+// slackEscape escapes the characters Slack's markup gives meaning to.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func writeSlack(res http.ResponseWriter, msg SlackMessage) {
+	res.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(res).Encode(msg); err != nil {
+		log.Printf("slack: %v", err)
+	}
+}
+
+// postSlack sends msg to a response_url, which has to be Slack's.
+func postSlack(responseURL string, msg SlackMessage) {
+	u, err := url.Parse(responseURL)
+	if err != nil || u.Scheme != "https" || u.Host != slackResponseHost {
+		log.Printf("slack: not posting to response_url %q", responseURL)
+		return
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("slack: %v", err)
+		return
+	}
+	res, err := slackClient.Post(u.String(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("slack: posting to response_url: %v", err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		log.Printf("slack: posting to response_url: %v", res.Status)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const slackTestSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+var (
+	// slackImgur stands in for the Imgur API.
+	slackImgur *testImgur
+
+	// slackPosts keeps what's posted to response_urls, by URL.
+	slackPostsMu sync.Mutex
+	slackPosts   map[string][]SlackMessage
+	slackPosted  chan struct{}
+
+	oldSlackClient *http.Client
+)
+
+// slackTestSetup points the Imgur client at a testImgur, has SlackHandler
+// trust slackTestSecret and keeps what it posts to response_urls rather
+// than sending it.
+func slackTestSetup() {
+	slackImgur = newTestImgur()
+	slackSigningSecret = slackTestSecret
+
+	slackPosts = make(map[string][]SlackMessage)
+	slackPosted = make(chan struct{}, 10)
+	oldSlackClient = slackClient
+	slackClient = &http.Client{Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+		var msg SlackMessage
+		json.NewDecoder(req.Body).Decode(&msg)
+		slackPostsMu.Lock()
+		slackPosts[req.URL.String()] = append(slackPosts[req.URL.String()], msg)
+		slackPostsMu.Unlock()
+		slackPosted <- struct{}{}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+	})}
+}
+
+func slackTestTeardown() {
+	slackImgur.Close()
+	slackSigningSecret = ""
+	slackClient = oldSlackClient
+}
+
+// slackRequest makes a request of form signed with the secret at ts, as
+// Slack would.
+func slackRequest(secret string, ts time.Time, form url.Values) *http.Request {
+	body := form.Encode()
+	req := httptest.NewRequest("POST", "http://dickbutt.in/integrations/slack", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func slackCommandForm(text, responseURL string) url.Values {
+	return url.Values{"command": {"/dickbutt"}, "text": {text}, "response_url": {responseURL}}
+}
+
+// serveSlack runs req through SlackHandler and decodes the response.
+func serveSlack(t *testing.T, req *http.Request) SlackMessage {
+	rec := httptest.NewRecorder()
+	SlackHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("SlackHandler returned status %v: %s", rec.Code, rec.Body)
+	}
+
+	var msg SlackMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("Decoding response returned error: %v", err)
+	}
+	return msg
+}
+
+// waitForSlackPost waits for something to be posted to a response_url.
+func waitForSlackPost(t *testing.T) {
+	select {
+	case <-slackPosted:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Nothing was posted to the response_url")
+	}
+}
+
+func TestSlackCommand(t *testing.T) {
+	slackTestSetup()
+	defer slackTestTeardown()
+
+	msg := serveSlack(t, slackRequest(slackTestSecret, time.Now(), slackCommandForm("Funny Cats", "https://hooks.slack.com/commands/1")))
+	if msg.ResponseType != "in_channel" || msg.Text != "funny-cats dickbutt" {
+		t.Errorf("SlackHandler returned %+v, want funny-cats in the channel", msg)
+	}
+	if len(msg.Blocks) != 3 || !strings.HasPrefix(msg.Blocks[0].ImageURL, "http://dickbutt.in"+permalinkPath) {
+		t.Fatalf("SlackHandler returned blocks %+v, want the composite, its credits and buttons", msg.Blocks)
+	}
+	want := "<http://imgur.com/gallery/zHQ2rzI|Of course, but maybe...> by kJerAFK on Imgur, 25 points"
+	if credit := msg.Blocks[1].Elements[0].(map[string]interface{})["text"]; credit != want {
+		t.Errorf("Credit is %v, want %v", credit, want)
+	}
+}
+
+func TestSlackUsage(t *testing.T) {
+	slackTestSetup()
+	defer slackTestTeardown()
+
+	msg := serveSlack(t, slackRequest(slackTestSecret, time.Now(), slackCommandForm("", "")))
+	if msg.ResponseType != "ephemeral" || !strings.HasPrefix(msg.Text, "Usage: /dickbutt") {
+		t.Errorf("SlackHandler returned %+v, want the usage", msg)
+	}
+}
+
+func TestSlackBadRequests(t *testing.T) {
+	slackTestSetup()
+	defer slackTestTeardown()
+
+	form := slackCommandForm("cats", "")
+	tampered := slackRequest(slackTestSecret, time.Now(), form)
+	tampered.Body = io.NopCloser(strings.NewReader(slackCommandForm("dogs", "").Encode()))
+	unsigned := slackRequest(slackTestSecret, time.Now(), form)
+	unsigned.Header.Del("X-Slack-Signature")
+
+	for name, req := range map[string]*http.Request{
+		"wrong secret":    slackRequest("not the secret", time.Now(), form),
+		"stale":           slackRequest(slackTestSecret, time.Now().Add(-slackMaxSkew-time.Minute), form),
+		"from the future": slackRequest(slackTestSecret, time.Now().Add(slackMaxSkew+time.Minute), form),
+		"tampered":        tampered,
+		"unsigned":        unsigned,
+	} {
+		rec := httptest.NewRecorder()
+		SlackHandler(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("SlackHandler returned status %v for a %s request, want %v", rec.Code, name, http.StatusUnauthorized)
+		}
+	}
+
+	// Within the window is fine.
+	req := slackRequest(slackTestSecret, time.Now().Add(-slackMaxSkew+time.Minute), form)
+	if _, err := verifySlackRequest(req, time.Now()); err != nil {
+		t.Errorf("verifySlackRequest returned error for a request %v old: %v", slackMaxSkew-time.Minute, err)
+	}
+}
+
+func TestSlackOff(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SlackHandler(rec, slackRequest("", time.Now(), slackCommandForm("cats", "")))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("SlackHandler without a signing secret returned status %v, want %v", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSlackDeferred(t *testing.T) {
+	slackTestSetup()
+	defer slackTestTeardown()
+	defer func(d time.Duration) { slackDeadline = d }(slackDeadline)
+	slackDeadline = time.Millisecond
+	slackImgur.SetDelay(100 * time.Millisecond)
+
+	const responseURL = "https://hooks.slack.com/commands/1"
+	msg := serveSlack(t, slackRequest(slackTestSecret, time.Now(), slackCommandForm("cats", responseURL)))
+	if msg.ResponseType != "ephemeral" || !strings.HasPrefix(msg.Text, "Looking for cats") {
+		t.Errorf("SlackHandler returned %+v, want it to say it's looking", msg)
+	}
+
+	waitForSlackPost(t)
+	slackPostsMu.Lock()
+	defer slackPostsMu.Unlock()
+	if posts := slackPosts[responseURL]; len(posts) != 1 || posts[0].ResponseType != "in_channel" || posts[0].Text != "cats dickbutt" {
+		t.Errorf("response_url was posted %+v, want the cats page", posts)
+	}
+}
+
+func TestSlackPost(t *testing.T) {
+	slackTestSetup()
+	defer slackTestTeardown()
+
+	page := Page{Place: "cats", ImgurSource: "https://i.imgur.com/zHQ2rzI.png", Top: 40, Bottom: 30, Overlay: defaultOverlay}
+	payload := fmt.Sprintf(`{"type":"block_actions","response_url":"https://hooks.slack.com/actions/1","user":{"id":"U1"},"actions":[{"action_id":"post","value":%q}]}`, permalinkToken(page))
+	rec := httptest.NewRecorder()
+	SlackHandler(rec, slackRequest(slackTestSecret, time.Now(), url.Values{"payload": {payload}}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("SlackHandler returned status %v: %s", rec.Code, rec.Body)
+	}
+
+	waitForSlackPost(t)
+	slackPostsMu.Lock()
+	defer slackPostsMu.Unlock()
+	posts := slackPosts["https://hooks.slack.com/actions/1"]
+	if len(posts) != 1 || !posts[0].ReplaceOriginal {
+		t.Fatalf("response_url was posted %+v, want the page in place of the original", posts)
+	}
+	// Posted, it loses its buttons and says who posted it.
+	last := posts[0].Blocks[len(posts[0].Blocks)-1]
+	if last.Type != "context" || fmt.Sprint(last.Elements) != "[map[text:Posted by <@U1> type:mrkdwn]]" {
+		t.Errorf("Last block is %+v, want who posted it", last)
+	}
+}
+
+func TestPostSlackHost(t *testing.T) {
+	slackTestSetup()
+	defer slackTestTeardown()
+
+	for _, u := range []string{
+		"http://hooks.slack.com/commands/1",
+		"https://hooks.slack.com.evil.example/commands/1",
+		"https://evil.example/commands/1",
+		"",
+	} {
+		postSlack(u, SlackMessage{Text: "hello"})
+	}
+	postSlack("https://hooks.slack.com/commands/1", SlackMessage{Text: "hello"})
+
+	slackPostsMu.Lock()
+	defer slackPostsMu.Unlock()
+	if len(slackPosts) != 1 || len(slackPosts["https://hooks.slack.com/commands/1"]) != 1 {
+		t.Errorf("postSlack posted to %v, want only hooks.slack.com over https", slackPosts)
+	}
+}
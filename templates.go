@@ -0,0 +1,197 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultTheme is the theme every other theme falls back on for the
+// templates it doesn't define itself.
+const defaultTheme = "default"
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// templateFuncs are available to every template.
+var templateFuncs = template.FuncMap{
+	"asset":     assets.URL,
+	"placePath": placePath,
+}
+
+var templates = newTemplates()
+
+// newTemplates loads the templates built into the binary or, when
+// TEMPLATE_DIR is set, from that directory, re-reading them whenever they
+// change so they can be worked on without rebuilding.
+func newTemplates() *Templates {
+	if dir := os.Getenv("TEMPLATE_DIR"); dir != "" {
+		t, err := NewTemplates(os.DirFS(dir), true)
+		if err != nil {
+			log.Fatalf("loading templates from %s: %v", dir, err)
+		}
+		return t
+	}
+
+	fsys, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		panic(err)
+	}
+	t, err := NewTemplates(fsys, false)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Templates holds the parsed templates of each theme.  A theme is a
+// directory of .html files, each defining one or more named templates; a
+// theme only needs to define the templates it changes from defaultTheme.
+type Templates struct {
+	fsys   fs.FS
+	reload bool // re-parse whenever a file in fsys changes
+
+	mu       sync.RWMutex
+	themes   map[string]*template.Template
+	modified time.Time // latest modification time of the parsed files
+}
+
+// NewTemplates parses the themes in fsys.
+func NewTemplates(fsys fs.FS, reload bool) (*Templates, error) {
+	t := &Templates{fsys: fsys, reload: reload}
+	if err := t.parse(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Templates) parse() error {
+	base, err := template.New(defaultTheme).Funcs(templateFuncs).ParseFS(t.fsys, defaultTheme+"/*.html")
+	if err != nil {
+		return err
+	}
+
+	dirs, err := fs.ReadDir(t.fsys, ".")
+	if err != nil {
+		return err
+	}
+
+	themes := map[string]*template.Template{defaultTheme: base}
+	for _, dir := range dirs {
+		if !dir.IsDir() || dir.Name() == defaultTheme {
+			continue
+		}
+		theme, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if theme, err = theme.ParseFS(t.fsys, dir.Name()+"/*.html"); err != nil {
+			return fmt.Errorf("theme %s: %v", dir.Name(), err)
+		}
+		themes[dir.Name()] = theme
+	}
+
+	modified, err := t.latestModification()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.themes = themes
+	t.modified = modified
+	t.mu.Unlock()
+	return nil
+}
+
+// latestModification returns the most recent modification time of the
+// template files.
+func (t *Templates) latestModification() (time.Time, error) {
+	var latest time.Time
+	err := fs.WalkDir(t.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// refresh re-parses the templates if any have changed since they were last
+// parsed.  A broken template is logged and the last good ones kept.
+func (t *Templates) refresh() {
+	modified, err := t.latestModification()
+	if err != nil {
+		log.Printf("checking templates: %v", err)
+		return
+	}
+
+	t.mu.RLock()
+	stale := !modified.Equal(t.modified)
+	t.mu.RUnlock()
+
+	if stale {
+		if err := t.parse(); err != nil {
+			log.Printf("reloading templates: %v", err)
+		}
+	}
+}
+
+// Has reports whether theme exists.
+func (t *Templates) Has(theme string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.themes[theme]
+	return ok
+}
+
+// Execute writes out the template name of theme, or of defaultTheme if
+// there's no such theme.
+func (t *Templates) Execute(w io.Writer, theme, name string, data interface{}) error {
+	if t.reload {
+		t.refresh()
+	}
+
+	t.mu.RLock()
+	templ, ok := t.themes[theme]
+	if !ok {
+		templ = t.themes[defaultTheme]
+	}
+	t.mu.RUnlock()
+
+	return templ.ExecuteTemplate(w, name, data)
+}
+
+// requestTheme returns the theme to show req in: the one named by ?theme=,
+// else the one for the host it was made to, else defaultTheme.
+func requestTheme(req *http.Request) string {
+	if theme := req.URL.Query().Get("theme"); theme != "" && templates.Has(theme) {
+		return theme
+	}
+
+	if theme, ok := hostSetting(themeHosts, req); ok {
+		return theme
+	}
+	return defaultTheme
+}
+
+// executeTemplate writes out the template name in the theme for req.
+func executeTemplate(res http.ResponseWriter, req *http.Request, name string, data interface{}) {
+	err := templates.Execute(res, requestTheme(req), name, data)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// executeString runs the template name of theme in ts.
+func executeString(t *testing.T, ts *Templates, theme, name string) string {
+	var buf bytes.Buffer
+	if err := ts.Execute(&buf, theme, name, nil); err != nil {
+		t.Fatalf("Execute(%v, %v) returned error: %v", theme, name, err)
+	}
+	return buf.String()
+}
+
+func TestTemplatesFallBack(t *testing.T) {
+	ts, err := NewTemplates(fstest.MapFS{
+		"default/page.html": {Data: []byte(`{{define "page"}}default page{{end}}`)},
+		"default/home.html": {Data: []byte(`{{define "home"}}default home{{end}}`)},
+		"dark/page.html":    {Data: []byte(`{{define "page"}}dark page{{end}}`)},
+	}, false)
+	if err != nil {
+		t.Fatalf("NewTemplates returned error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		theme, name, want string
+	}{
+		{"default", "page", "default page"},
+		{"dark", "page", "dark page"},
+		{"dark", "home", "default home"},
+		{"missing", "page", "default page"},
+	} {
+		if got := executeString(t, ts, tt.theme, tt.name); got != tt.want {
+			t.Errorf("Execute(%v, %v) wrote %q, want %q", tt.theme, tt.name, got, tt.want)
+		}
+	}
+	if !ts.Has("dark") || ts.Has("missing") {
+		t.Errorf("Has(dark) = %v and Has(missing) = %v, want true and false", ts.Has("dark"), ts.Has("missing"))
+	}
+}
+
+func TestTemplatesReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "default", "page.html")
+	write := func(content string, modified time.Time) {
+		if err := os.WriteFile(page, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(page, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Mkdir(filepath.Dir(page), 0755)
+	start := time.Now().Add(-time.Hour)
+	write(`{{define "page"}}one{{end}}`, start)
+
+	ts, err := NewTemplates(os.DirFS(dir), true)
+	if err != nil {
+		t.Fatalf("NewTemplates returned error: %v", err)
+	}
+	if got := executeString(t, ts, defaultTheme, "page"); got != "one" {
+		t.Fatalf("Execute wrote %q, want one", got)
+	}
+
+	write(`{{define "page"}}two{{end}}`, start.Add(time.Minute))
+	if got := executeString(t, ts, defaultTheme, "page"); got != "two" {
+		t.Errorf("Execute after a change wrote %q, want two", got)
+	}
+
+	// A broken template leaves the last good ones in place.
+	write(`{{define "page"}}three{{end`, start.Add(2*time.Minute))
+	if got := executeString(t, ts, defaultTheme, "page"); got != "two" {
+		t.Errorf("Execute after a bad change wrote %q, want two", got)
+	}
+}
+
+func TestRequestTheme(t *testing.T) {
+	defer func(old map[string]string) { themeHosts = old }(themeHosts)
+	themeHosts = parseHostMap("dickbutt.in=classic, plain.example=default")
+
+	withSiteDomains([]string{"dickbutt.in"}, func() {
+		for _, tt := range []struct {
+			url, want string
+		}{
+			{"http://example.com/cats", defaultTheme},
+			{"http://example.com/cats?theme=classic", "classic"},
+			{"http://example.com/cats?theme=missing", defaultTheme},
+			{"http://dickbutt.in/cats", "classic"},
+			{"http://cats.dickbutt.in/", "classic"},
+			{"http://dickbutt.in/cats?theme=default", defaultTheme},
+			{"http://plain.example/cats", defaultTheme},
+		} {
+			if got := requestTheme(httptest.NewRequest("GET", tt.url, nil)); got != tt.want {
+				t.Errorf("requestTheme for %v returned %v, want %v", tt.url, got, tt.want)
+			}
+		}
+	})
+}
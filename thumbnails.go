@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bitbucket.org/liamstask/go-imgur/imgur"
+
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// clientHints are the request headers the background size is picked from,
+// advertised in Accept-CH and listed in Vary.
+const clientHints = "Sec-CH-Viewport-Width, Sec-CH-DPR, Viewport-Width, DPR"
+
+// backgroundThumbs are the thumbnail sizes that keep an image's proportions,
+// smallest first; the square ones would crop the background.
+var backgroundThumbs = []string{imgur.ThumbMedium, imgur.ThumbLarge, imgur.ThumbHuge}
+
+// sizeParams are the values ?size= accepts, and the thumbnail size they
+// stand for.  "original" is the full size image.
+var sizeParams = map[string]string{
+	"medium":   imgur.ThumbMedium,
+	"large":    imgur.ThumbLarge,
+	"huge":     imgur.ThumbHuge,
+	"original": "",
+}
+
+// clientWidth returns the width in device pixels the client will show the
+// page at, going by its client hints, or 0 if it didn't send any.
+func clientWidth(req *http.Request) int {
+	width := hintValue(req, "Sec-CH-Viewport-Width", "Viewport-Width")
+	if width <= 0 {
+		return 0
+	}
+	dpr := hintValue(req, "Sec-CH-DPR", "DPR")
+	if dpr <= 0 {
+		dpr = 1
+	}
+	return int(math.Ceil(width * dpr))
+}
+
+// hintValue returns the first of the named client hints that's set.
+func hintValue(req *http.Request, names ...string) float64 {
+	for _, name := range names {
+		if v := req.Header.Get(name); v != "" {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err == nil {
+				return f
+			}
+		}
+	}
+	return 0
+}
+
+// pickThumb picks the thumbnail size bg should be shown at for req, or ""
+// for the original.  ?size= wins, then the client hints, falling back to
+// the biggest thumbnail; the original is used whenever it's no bigger than
+// the thumbnail would be.
+func pickThumb(req *http.Request, bg Background) string {
+	if size, ok := sizeParams[req.URL.Query().Get("size")]; ok {
+		return size
+	}
+
+	// Only i.imgur.com has thumbnails, and those of animated images are
+	// stills.
+	if !strings.HasPrefix(proxyURL(bg.Link), "/img/") || bg.Animated || path.Ext(bg.Link) == ".gif" {
+		return ""
+	}
+
+	want := clientWidth(req)
+	if want == 0 {
+		want = imgur.ThumbSize(imgur.ThumbHuge)
+	}
+
+	longest := bg.Width
+	if bg.Height > longest {
+		longest = bg.Height
+	}
+
+	for _, size := range backgroundThumbs {
+		px := imgur.ThumbSize(size)
+		if longest != 0 && px >= longest {
+			return ""
+		}
+		if thumbWidth(bg, px) >= want {
+			return size
+		}
+	}
+	return ""
+}
+
+// thumbWidth returns the width of bg's thumbnail that fits in a px square;
+// a portrait image's is narrower than px.
+func thumbWidth(bg Background, px int) int {
+	if bg.Height > bg.Width && bg.Width > 0 {
+		return px * bg.Width / bg.Height
+	}
+	return px
+}
+
+// backgroundSrcset returns a srcset offering bg in each thumbnail size
+// smaller than the original, and the original itself, all through the
+// image proxy.
+func backgroundSrcset(bg Background) string {
+	if bg.Animated || path.Ext(bg.Link) == ".gif" || bg.Width == 0 || bg.Height == 0 {
+		return ""
+	}
+
+	var set []string
+	for _, size := range backgroundThumbs {
+		px := imgur.ThumbSize(size)
+		if px >= bg.Width && px >= bg.Height {
+			break
+		}
+		set = append(set, fmt.Sprintf("%s %dw", proxyURL(imgur.ThumbnailURL(bg.Link, size)), thumbWidth(bg, px)))
+	}
+	set = append(set, fmt.Sprintf("%s %dw", proxyURL(bg.Link), bg.Width))
+
+	return strings.Join(set, ", ")
+}
+
+// sizeBackground returns bg through the image proxy at the size picked for
+// req, and the srcset offering its other sizes.
+func sizeBackground(req *http.Request, bg Background) (src, srcset string) {
+	return proxyURL(imgur.ThumbnailURL(bg.Link, pickThumb(req, bg))), backgroundSrcset(bg)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPickThumb(t *testing.T) {
+	big := Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 2000, Height: 1500}
+	small := Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 500, Height: 400}
+	portrait := Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 1000, Height: 2000}
+
+	for _, tt := range []struct {
+		name    string
+		bg      Background
+		query   string
+		headers map[string]string
+		want    string
+	}{
+		{"no hints", big, "", nil, "h"},
+		{"Viewport-Width", big, "", map[string]string{"Viewport-Width": "300"}, "m"},
+		{"Viewport-Width and DPR", big, "", map[string]string{"Viewport-Width": "300", "DPR": "2"}, "l"},
+		{"Sec-CH hints", big, "", map[string]string{"Sec-CH-Viewport-Width": "300", "Sec-CH-DPR": "2"}, "l"},
+		{"Sec-CH hints first", big, "", map[string]string{"Sec-CH-Viewport-Width": "300", "Viewport-Width": "1000"}, "m"},
+		{"wider than any thumbnail", big, "", map[string]string{"Viewport-Width": "400", "DPR": "3"}, ""},
+		{"bad hints", big, "", map[string]string{"Viewport-Width": "wide", "DPR": "-1"}, "h"},
+		{"?size=", big, "?size=medium", map[string]string{"Viewport-Width": "1000"}, "m"},
+		{"?size=original", big, "?size=original", nil, ""},
+		{"unknown ?size=", big, "?size=enormous", map[string]string{"Viewport-Width": "300"}, "m"},
+		{"original no bigger", small, "", nil, ""},
+		{"original no bigger than wanted", small, "", map[string]string{"Viewport-Width": "1000"}, ""},
+		{"original bigger than wanted", small, "", map[string]string{"Viewport-Width": "200"}, "m"},
+		{"portrait", portrait, "", map[string]string{"Viewport-Width": "300"}, "l"},
+		{"animated", Background{Link: big.Link, Width: 2000, Height: 1500, Animated: true}, "", nil, ""},
+		{"gif", Background{Link: "https://i.imgur.com/zHQ2rzI.gif", Width: 2000, Height: 1500}, "", nil, ""},
+		{"not on i.imgur.com", Background{Link: "https://s.imgur.com/images/OverCapacity_700.png"}, "", nil, ""},
+	} {
+		req := httptest.NewRequest("GET", "/cats"+tt.query, nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		if got := pickThumb(req, tt.bg); got != tt.want {
+			t.Errorf("pickThumb with %v returned %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBackgroundSrcset(t *testing.T) {
+	for _, tt := range []struct {
+		bg   Background
+		want string
+	}{
+		{Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 2000, Height: 1500},
+			"/img/zHQ2rzIm.png 320w, /img/zHQ2rzIl.png 640w, /img/zHQ2rzIh.png 1024w, /img/zHQ2rzI.png 2000w"},
+		{Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 500, Height: 400},
+			"/img/zHQ2rzIm.png 320w, /img/zHQ2rzI.png 500w"},
+		{Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 1000, Height: 2000},
+			"/img/zHQ2rzIm.png 160w, /img/zHQ2rzIl.png 320w, /img/zHQ2rzIh.png 512w, /img/zHQ2rzI.png 1000w"},
+		{Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 300, Height: 200}, "/img/zHQ2rzI.png 300w"},
+		{Background{Link: "https://i.imgur.com/zHQ2rzI.png"}, ""},
+		{Background{Link: "https://i.imgur.com/zHQ2rzI.png", Width: 2000, Height: 1500, Animated: true}, ""},
+		{Background{Link: "https://i.imgur.com/zHQ2rzI.gif", Width: 2000, Height: 1500}, ""},
+	} {
+		if got := backgroundSrcset(tt.bg); got != tt.want {
+			t.Errorf("backgroundSrcset of %vx%v %v returned %q, want %q", tt.bg.Width, tt.bg.Height, tt.bg.Link, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"log"
 	"net/http"
 	"os"
 )
@@ -9,6 +10,11 @@ func main() {
 	r := setupRouter()
 	http.Handle("/", r)
 
+	if scheduler != nil {
+		log.Printf("scheduling %v", scheduler)
+		scheduler.Start()
+	}
+
 	err := http.ListenAndServe(":"+os.Getenv("PORT"), nil)
 
 	if err != nil {
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// The sources a Scheduler picks its place from.
+const (
+	sourcePlaces   = "places"   // one of its Places
+	sourceTrending = "trending" // one of the trending places, else one of its Places
+	sourceRandom   = "random"   // Imgur's random gallery
+)
+
+const (
+	// maxPicks is how many pages a Scheduler tries before giving up on
+	// finding one with a background that renders.
+	maxPicks = 3
+
+	// maxDeliveries is how many deliveries a Scheduler remembers.
+	maxDeliveries = 100
+)
+
+//go:embed webhooks
+var webhookTemplates embed.FS
+
+// webhookFuncs are available to every payload template.
+var webhookFuncs = template.FuncMap{
+	"json":          webhookJSON,
+	"slackCredit":   webhookSlackCredit,
+	"discordCredit": webhookDiscordCredit,
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+var errNoPage = errors.New("no page with a background")
+
+// scheduler posts the dickbutt of the day to the webhooks configured in
+// WEBHOOKS_FILE; there isn't one without it.
+var scheduler = mustLoadScheduler(os.Getenv("WEBHOOKS_FILE"))
+
+// Scheduler posts a page to its Targets once a day, at At in Location.
+type Scheduler struct {
+	At       time.Time // Only the hour and minute count
+	Location *time.Location
+	Site     *url.URL // Where the pages posted are linked to
+	Source   string
+	Places   []string
+	Targets  []*WebhookTarget
+	Attempts int           // How many times a delivery is tried
+	Backoff  time.Duration // How long before the first retry, doubling after
+
+	mu         sync.Mutex
+	deliveries []Delivery // most recent last
+}
+
+// WebhookTarget is somewhere a Scheduler posts to.  Its payload is the
+// template for its Format, from the webhooks directory, unless it has a
+// Template of its own.
+type WebhookTarget struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Format      string `json:"format"` // "slack", "discord" or "json"
+	Template    string `json:"template"`
+	ContentType string `json:"content_type"`
+
+	templ *template.Template
+}
+
+// webhookConfig is the format of WEBHOOKS_FILE, e.g.
+//
+//	{
+//		"at": "09:00",
+//		"timezone": "America/New_York",
+//		"site": "https://dickbutt.in",
+//		"source": "trending",
+//		"places": ["cats", "butts+bees"],
+//		"targets": [
+//			{"name": "general", "url": "https://hooks.slack.com/services/…", "format": "slack"},
+//			{"name": "memes", "url": "https://discord.com/api/webhooks/…", "format": "discord"}
+//		]
+//	}
+type webhookConfig struct {
+	At       string           `json:"at"`
+	Timezone string           `json:"timezone"`
+	Site     string           `json:"site"`
+	Source   string           `json:"source"`
+	Places   []string         `json:"places"`
+	Targets  []*WebhookTarget `json:"targets"`
+	Attempts int              `json:"attempts"`
+	Backoff  string           `json:"backoff"`
+}
+
+// Delivery is one attempt at posting to a target.
+type Delivery struct {
+	Time    time.Time
+	Target  string
+	Place   string
+	Attempt int
+	Status  int // 0 if there was no response
+	Err     string
+}
+
+func (d Delivery) String() string {
+	result := http.StatusText(d.Status)
+	if d.Err != "" {
+		result = d.Err
+	}
+	return fmt.Sprintf("webhook %s: %s attempt %d: %d %s", d.Target, d.Place, d.Attempt, d.Status, result)
+}
+
+// webhookData is what payload templates are executed with.
+type webhookData struct {
+	Date     string // In the Scheduler's Location, as 2006-01-02
+	Place    string
+	Title    string
+	PageURL  string
+	ImageURL string
+	Width    int
+	Height   int
+	Credits  []webhookCredit
+}
+
+// webhookCredit credits a background; Place is only set for a mashup's
+// panels.
+type webhookCredit struct {
+	Place     string `json:"place,omitempty"`
+	Title     string `json:"title"`
+	Author    string `json:"author,omitempty"`
+	Permalink string `json:"permalink"`
+	Points    int    `json:"points"`
+}
+
+func mustLoadScheduler(name string) *Scheduler {
+	if name == "" {
+		return nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		log.Fatalf("WEBHOOKS_FILE: %v", err)
+	}
+	s, err := NewScheduler(data)
+	if err != nil {
+		log.Fatalf("WEBHOOKS_FILE %s: %v", name, err)
+	}
+	return s
+}
+
+// NewScheduler makes a Scheduler from its webhookConfig.
+func NewScheduler(config []byte) (*Scheduler, error) {
+	var c webhookConfig
+	if err := json.Unmarshal(config, &c); err != nil {
+		return nil, err
+	}
+
+	s := &Scheduler{Source: c.Source, Targets: c.Targets, Attempts: c.Attempts, Backoff: 30 * time.Second}
+
+	var err error
+	if c.At == "" {
+		c.At = "12:00"
+	}
+	if s.At, err = time.Parse("15:04", c.At); err != nil {
+		return nil, fmt.Errorf("at: %v", err)
+	}
+	if s.Location, err = time.LoadLocation(c.Timezone); err != nil {
+		return nil, fmt.Errorf("timezone: %v", err)
+	}
+	if c.Backoff != "" {
+		if s.Backoff, err = time.ParseDuration(c.Backoff); err != nil {
+			return nil, fmt.Errorf("backoff: %v", err)
+		}
+	}
+	if s.Attempts <= 0 {
+		s.Attempts = 3
+	}
+
+	if c.Site == "" && len(siteDomains) > 0 {
+		c.Site = "https://" + siteDomains[0]
+	}
+	if s.Site, err = url.Parse(c.Site); err != nil || s.Site.Host == "" {
+		return nil, fmt.Errorf("site: %q isn't a URL", c.Site)
+	}
+
+	for _, place := range c.Places {
+		if places := CanonicalMashup(place); len(places) > 0 {
+			s.Places = append(s.Places, mashupPlace(places))
+		}
+	}
+	switch s.Source {
+	case "":
+		s.Source = sourceRandom
+		if len(s.Places) > 0 {
+			s.Source = sourcePlaces
+		}
+	case sourcePlaces:
+		if len(s.Places) == 0 {
+			return nil, errors.New("source: places without any places")
+		}
+	case sourceTrending, sourceRandom:
+	default:
+		return nil, fmt.Errorf("source: no such source %q", s.Source)
+	}
+
+	if len(s.Targets) == 0 {
+		return nil, errors.New("no targets")
+	}
+	for i, t := range s.Targets {
+		if t.Name == "" {
+			t.Name = fmt.Sprint(i)
+		}
+		if err := t.parse(); err != nil {
+			return nil, fmt.Errorf("target %s: %v", t.Name, err)
+		}
+	}
+	return s, nil
+}
+
+// parse checks t's URL and parses its template.
+func (t *WebhookTarget) parse() error {
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q isn't a URL", t.URL)
+	}
+	if t.ContentType == "" {
+		t.ContentType = "application/json"
+	}
+
+	templ := template.New(t.Name).Funcs(webhookFuncs)
+	if t.Template != "" {
+		t.templ, err = templ.Parse(t.Template)
+	} else if t.Format != "" {
+		t.templ, err = templ.ParseFS(webhookTemplates, "webhooks/"+t.Format+".json")
+		if err == nil {
+			t.templ = t.templ.Lookup(t.Format + ".json")
+		}
+	} else {
+		err = errors.New("neither a format nor a template")
+	}
+	return err
+}
+
+// payload executes t's template with data.  A JSON payload has to come out
+// as JSON, which a template of the target's own mightn't.
+func (t *WebhookTarget) payload(data webhookData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.templ.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	if t.ContentType == "application/json" && !json.Valid(buf.Bytes()) {
+		return nil, errors.New("template didn't make JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// Start posts every day, from now on.
+func (s *Scheduler) Start() {
+	go func() {
+		for {
+			time.Sleep(time.Until(s.Next(time.Now())))
+			if err := s.Post(time.Now()); err != nil {
+				log.Printf("dickbutt of the day: %v", err)
+			}
+		}
+	}()
+}
+
+// Next returns when s next posts after now.
+func (s *Scheduler) Next(now time.Time) time.Time {
+	now = now.In(s.Location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.At.Hour(), s.At.Minute(), 0, 0, s.Location)
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, s.At.Hour(), s.At.Minute(), 0, 0, s.Location)
+	}
+	return next
+}
+
+// Post picks the dickbutt of the day and posts it to every target,
+// returning once they've all had it or run out of attempts.
+func (s *Scheduler) Post(now time.Time) error {
+	req := s.request()
+	p, err := s.pickPage(req)
+	if err != nil {
+		return err
+	}
+
+	page, image := pagePermalink(p)
+	data := webhookData{
+		Date:     now.In(s.Location).Format("2006-01-02"),
+		Place:    p.Place,
+		Title:    p.Place + " dickbutt",
+		PageURL:  absURL(req, page),
+		ImageURL: absURL(req, image),
+		Width:    p.Width,
+		Height:   p.Height,
+		Credits:  []webhookCredit{},
+	}
+	if p.Permalink != "" {
+		data.Credits = append(data.Credits, webhookCredit{"", p.Title, p.Author, p.Permalink, p.Points})
+	}
+	for _, panel := range p.Panels {
+		if panel.Permalink != "" {
+			data.Credits = append(data.Credits, webhookCredit{panel.Place, panel.Title, panel.Author, panel.Permalink, panel.Points})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range s.Targets {
+		body, err := t.payload(data)
+		if err != nil {
+			s.record(Delivery{Time: time.Now(), Target: t.Name, Place: p.Place, Err: err.Error()})
+			continue
+		}
+		wg.Add(1)
+		go func(t *WebhookTarget) {
+			defer wg.Done()
+			s.deliver(t, p.Place, body)
+		}(t)
+	}
+	wg.Wait()
+	return nil
+}
+
+// request is a stand-in for a request to the site, for the pages posted to
+// be made as if they'd been asked for there.
+func (s *Scheduler) request() *http.Request {
+	req, err := http.NewRequest("GET", s.Site.Scheme+"://"+s.Site.Host+"/", nil)
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("X-Forwarded-Proto", s.Site.Scheme)
+	return req
+}
+
+// pickPage makes a page for a place from s's Source, trying again if Imgur
+// has nothing for it or its composite won't render.
+func (s *Scheduler) pickPage(req *http.Request) (Page, error) {
+	for i := 0; i < maxPicks; i++ {
+		var p Page
+		if place := s.pickPlace(); place != "" {
+			p = newPlacePage(req, CanonicalMashup(place))
+		} else {
+			p = NewPage(req, "random", RandomSearcher())
+		}
+		if !hasBackgrounds(p) {
+			continue
+		}
+		if _, err := Composite(p); err != nil {
+			log.Printf("dickbutt of the day: %s: %v", p.Place, err)
+			continue
+		}
+		return p, nil
+	}
+	return Page{}, errNoPage
+}
+
+// pickPlace returns a place from s's Source, or "" for the random gallery.
+func (s *Scheduler) pickPlace() string {
+	places := s.Places
+	switch s.Source {
+	case sourceRandom:
+		return ""
+	case sourceTrending:
+		var trending []string
+		for _, place := range placeStats.Trending(10) {
+			if !isReservedPlace(place) {
+				trending = append(trending, place)
+			}
+		}
+		if len(trending) > 0 {
+			places = trending
+		}
+	}
+	if len(places) == 0 {
+		return ""
+	}
+	return places[rand.Intn(len(places))]
+}
+
+// hasBackgrounds reports whether Imgur found a background for all of p.
+func hasBackgrounds(p Page) bool {
+	if len(p.Panels) == 0 {
+		return p.ImgurSource != overCapacity
+	}
+	for _, panel := range p.Panels {
+		if panel.ImgurSource == overCapacity {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver posts body to t, retrying with exponential backoff after errors
+// that might not happen again: failing to connect, 5xx and 429.
+func (s *Scheduler) deliver(t *WebhookTarget, place string, body []byte) {
+	backoff := s.Backoff
+	for attempt := 1; attempt <= s.Attempts; attempt++ {
+		d := Delivery{Time: time.Now(), Target: t.Name, Place: place, Attempt: attempt}
+		res, err := webhookClient.Post(t.URL, t.ContentType, bytes.NewReader(body))
+		if err != nil {
+			d.Err = err.Error()
+		} else {
+			res.Body.Close()
+			d.Status = res.StatusCode
+		}
+		s.record(d)
+
+		if err == nil && (d.Status < 500 && d.Status != http.StatusTooManyRequests) {
+			return
+		}
+		if attempt < s.Attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// record logs d, and remembers it.
+func (s *Scheduler) record(d Delivery) {
+	log.Print(d)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, d)
+	if len(s.deliveries) > maxDeliveries {
+		s.deliveries = s.deliveries[len(s.deliveries)-maxDeliveries:]
+	}
+}
+
+// Deliveries returns the deliveries s remembers, oldest first.
+func (s *Scheduler) Deliveries() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Delivery(nil), s.deliveries...)
+}
+
+func webhookJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// webhookSlackCredit is slackCredit, naming the panel's place if it's one.
+func webhookSlackCredit(c webhookCredit) string {
+	credit := slackCredit(c.Title, c.Author, c.Permalink, c.Points)
+	if c.Place != "" {
+		credit = slackEscape(c.Place) + ": " + credit
+	}
+	return credit
+}
+
+func webhookDiscordCredit(c webhookCredit) string {
+	return discordCredit(c.Title, c.Author, c.Permalink, c.Points)
+}
+
+// String lists the targets by name, for the startup log.
+func (s *Scheduler) String() string {
+	names := make([]string, len(s.Targets))
+	for i, t := range s.Targets {
+		names[i] = t.Name
+	}
+	return fmt.Sprintf("dickbutt of the day at %s %s to %s", s.At.Format("15:04"), s.Location, strings.Join(names, ", "))
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	// webhookImgur stands in for the Imgur API and its images.
+	webhookImgur *testImgur
+
+	// receiver stands in for the webhooks, keeping what each path is sent
+	// and answering with the statuses in receiverStatuses, then 200.
+	receiver         *httptest.Server
+	receiverMu       sync.Mutex
+	receiverBodies   map[string][]string
+	receiverStatuses map[string][]int
+)
+
+// webhookTestSetup points the Imgur client at a testImgur and starts the
+// receiver.
+func webhookTestSetup() {
+	webhookImgur = newTestImgur()
+
+	receiverBodies = make(map[string][]string)
+	receiverStatuses = make(map[string][]int)
+	receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiverMu.Lock()
+		defer receiverMu.Unlock()
+		receiverBodies[r.URL.Path] = append(receiverBodies[r.URL.Path], string(body))
+		if statuses := receiverStatuses[r.URL.Path]; len(statuses) > 0 {
+			receiverStatuses[r.URL.Path] = statuses[1:]
+			w.WriteHeader(statuses[0])
+		}
+	}))
+}
+
+func webhookTestTeardown() {
+	webhookImgur.Close()
+	receiver.Close()
+}
+
+// testScheduler makes a Scheduler posting to the receiver's /slack, /discord
+// and /json.
+func testScheduler(t *testing.T) *Scheduler {
+	config := fmt.Sprintf(`{
+		"site": "https://dickbutt.in",
+		"places": ["Funny Cats"],
+		"backoff": "1ms",
+		"targets": [
+			{"name": "slack", "url": "%[1]s/slack", "format": "slack"},
+			{"name": "discord", "url": "%[1]s/discord", "format": "discord"},
+			{"name": "json", "url": "%[1]s/json", "format": "json"}
+		]
+	}`, receiver.URL)
+	s, err := NewScheduler([]byte(config))
+	if err != nil {
+		t.Fatalf("NewScheduler returned error: %v", err)
+	}
+	return s
+}
+
+func TestSchedulerPost(t *testing.T) {
+	webhookTestSetup()
+	defer webhookTestTeardown()
+
+	s := testScheduler(t)
+	if err := s.Post(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Post returned error: %v", err)
+	}
+
+	var got struct {
+		Date     string          `json:"date"`
+		Place    string          `json:"place"`
+		PageURL  string          `json:"page_url"`
+		ImageURL string          `json:"image_url"`
+		Width    int             `json:"width"`
+		Credits  []webhookCredit `json:"credits"`
+	}
+	if len(receiverBodies["/json"]) != 1 {
+		t.Fatalf("/json was sent %+v, want one payload", receiverBodies["/json"])
+	}
+	if err := json.Unmarshal([]byte(receiverBodies["/json"][0]), &got); err != nil {
+		t.Fatalf("Decoding JSON payload returned error: %v", err)
+	}
+	if got.Date != "2024-03-01" || got.Place != "funny-cats" || got.Width != 61 {
+		t.Errorf("JSON payload is %+v, want funny-cats, 61 wide, on 2024-03-01", got)
+	}
+	if !strings.HasPrefix(got.PageURL, "https://dickbutt.in"+permalinkPath) || got.ImageURL != got.PageURL+".png" {
+		t.Errorf("JSON payload links %v and %v, want a permalink and its composite", got.PageURL, got.ImageURL)
+	}
+	want := webhookCredit{Title: "Of course, but maybe...", Author: "kJerAFK", Permalink: "http://imgur.com/gallery/zHQ2rzI", Points: 25}
+	if len(got.Credits) != 1 || got.Credits[0] != want {
+		t.Errorf("JSON payload credits %+v, want %+v", got.Credits, want)
+	}
+
+	for _, path := range []string{"/slack", "/discord"} {
+		if len(receiverBodies[path]) != 1 || !json.Valid([]byte(receiverBodies[path][0])) {
+			t.Errorf("%s was sent %+v, want one JSON payload", path, receiverBodies[path])
+		}
+	}
+	var slack SlackMessage
+	json.Unmarshal([]byte(receiverBodies["/slack"][0]), &slack)
+	if len(slack.Blocks) != 2 || slack.Blocks[0].ImageURL != got.ImageURL {
+		t.Errorf("Slack payload is %+v, want the composite and its credits", slack)
+	}
+	var discord DiscordResponseData
+	json.Unmarshal([]byte(receiverBodies["/discord"][0]), &discord)
+	if len(discord.Embeds) != 1 || discord.Embeds[0].URL != got.PageURL || discord.Embeds[0].Image.URL != got.ImageURL {
+		t.Errorf("Discord payload is %+v, want an embed of the permalink", discord)
+	}
+
+	if n := len(s.Deliveries()); n != 3 {
+		t.Errorf("Deliveries returned %v deliveries, want 3", n)
+	}
+}
+
+func TestSchedulerRetries(t *testing.T) {
+	webhookTestSetup()
+	defer webhookTestTeardown()
+
+	receiverStatuses["/slack"] = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+	receiverStatuses["/discord"] = []int{http.StatusBadRequest}
+	receiverStatuses["/json"] = []int{500, 500, 500, 500}
+
+	s := testScheduler(t)
+	if err := s.Post(time.Now()); err != nil {
+		t.Fatalf("Post returned error: %v", err)
+	}
+
+	// Errors the target might get over are retried, up to Attempts times;
+	// others aren't.
+	statuses := make(map[string][]int)
+	for _, d := range s.Deliveries() {
+		statuses[d.Target] = append(statuses[d.Target], d.Status)
+	}
+	for target, want := range map[string][]int{
+		"slack":   {503, 429, 200},
+		"discord": {400},
+		"json":    {500, 500, 500},
+	} {
+		if fmt.Sprint(statuses[target]) != fmt.Sprint(want) {
+			t.Errorf("Deliveries to %s got %v, want %v", target, statuses[target], want)
+		}
+	}
+}
+
+func TestSchedulerTemplate(t *testing.T) {
+	webhookTestSetup()
+	defer webhookTestTeardown()
+
+	config := fmt.Sprintf(`{
+		"site": "https://dickbutt.in",
+		"places": ["cats"],
+		"targets": [
+			{"url": "%[1]s/text", "template": "{{.Title}} {{.Date}}", "content_type": "text/plain"},
+			{"url": "%[1]s/broken", "template": "{{.Title}}"}
+		]
+	}`, receiver.URL)
+	s, err := NewScheduler([]byte(config))
+	if err != nil {
+		t.Fatalf("NewScheduler returned error: %v", err)
+	}
+	if err := s.Post(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Post returned error: %v", err)
+	}
+
+	if want := []string{"cats dickbutt 2024-03-01"}; fmt.Sprint(receiverBodies["/text"]) != fmt.Sprint(want) {
+		t.Errorf("/text was sent %+v, want %+v", receiverBodies["/text"], want)
+	}
+	// A JSON target whose template doesn't make JSON isn't sent anything.
+	if len(receiverBodies["/broken"]) != 0 {
+		t.Errorf("/broken was sent %+v, want nothing", receiverBodies["/broken"])
+	}
+}
+
+func TestSchedulerNext(t *testing.T) {
+	s, err := NewScheduler([]byte(`{"at": "09:30", "site": "https://dickbutt.in", "targets": [{"url": "http://localhost/", "format": "json"}]}`))
+	if err != nil {
+		t.Fatalf("NewScheduler returned error: %v", err)
+	}
+
+	for _, tt := range []struct{ now, want time.Time }{
+		{time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), time.Date(2024, 3, 2, 9, 30, 0, 0, time.UTC)},
+		{time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)},
+	} {
+		if got := s.Next(tt.now); !got.Equal(tt.want) {
+			t.Errorf("Next(%v) returned %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestNewSchedulerErrors(t *testing.T) {
+	for _, config := range []string{
+		`{"site": "https://dickbutt.in"}`,
+		`{"at": "9am", "site": "https://dickbutt.in", "targets": [{"url": "http://localhost/", "format": "json"}]}`,
+		`{"source": "hot", "site": "https://dickbutt.in", "targets": [{"url": "http://localhost/", "format": "json"}]}`,
+		`{"source": "places", "site": "https://dickbutt.in", "targets": [{"url": "http://localhost/", "format": "json"}]}`,
+		`{"site": "https://dickbutt.in", "targets": [{"url": "http://localhost/", "format": "teams"}]}`,
+		`{"site": "https://dickbutt.in", "targets": [{"url": "localhost", "format": "json"}]}`,
+		`{"targets": [{"url": "http://localhost/", "format": "json"}]}`,
+	} {
+		if _, err := NewScheduler([]byte(config)); err == nil {
+			t.Errorf("NewScheduler(%s) returned no error", config)
+		}
+	}
+}